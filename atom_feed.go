@@ -0,0 +1,131 @@
+package main
+
+import (
+	"cmp"
+	"html"
+	"strings"
+)
+
+// AtomFeed models the subset of an Atom 1.0 document (RFC 4287) that gator cares
+// about. Atom feeds are converted to RSSFeed right after decoding so the rest of the
+// program only has to deal with a single feed model.
+type AtomFeed struct {
+	Title     AtomText    `xml:"title"`
+	Subtitle  AtomText    `xml:"subtitle"`
+	Links     []AtomLink  `xml:"link"`
+	Lang      string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Icon      string      `xml:"icon"`
+	Logo      string      `xml:"logo"`
+	Generator string      `xml:"generator"`
+	Entries   []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string       `xml:"id"`
+	Title     AtomText     `xml:"title"`
+	Links     []AtomLink   `xml:"link"`
+	Summary   AtomText     `xml:"summary"`
+	Content   AtomText     `xml:"content"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Authors   []AtomPerson `xml:"author"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// AtomText holds an Atom text construct. Its contents are plain text or escaped HTML
+// when `type` is "text" or "html", and inline XHTML markup when `type` is "xhtml".
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// HTML returns the contents of the text construct as HTML, escaping them when they're
+// plain text.
+func (t AtomText) HTML() string {
+	if t.Type == "" || t.Type == "text" {
+		return html.EscapeString(t.String())
+	}
+	return t.String()
+}
+
+// alternateLink returns the URL of the first link with relation "alternate" (the
+// default relation when none is given). If there's none, it falls back to the first
+// link with a non-empty href.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if (link.Rel == "" || link.Rel == "alternate") && link.Href != "" {
+			return link.Href
+		}
+	}
+	for _, link := range links {
+		if link.Href != "" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// toRSS maps the Atom feed into the RSS feed model used by scrapeFeeds. Entries use
+// their content as description when there's no summary, and their publication date
+// when available, falling back to the date of their last update.
+func (a *AtomFeed) toRSS() *RSSFeed {
+	var rss RSSFeed
+	rss.Channel.Title = a.Title.String()
+	rss.Channel.Link = alternateLink(a.Links)
+	rss.Channel.Description = a.Subtitle.String()
+	rss.Channel.Language = a.Lang
+	rss.Channel.Generator = strings.TrimSpace(a.Generator)
+	rss.Channel.Image.URL = cmp.Or(strings.TrimSpace(a.Logo), strings.TrimSpace(a.Icon))
+
+	for _, entry := range a.Entries {
+		description := entry.Summary.HTML()
+		if description == "" {
+			description = entry.Content.HTML()
+		}
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+		var enclosures []RSSEnclosure
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				enclosures = append(enclosures, RSSEnclosure{URL: link.Href, Length: link.Length, Type: link.Type})
+			}
+		}
+		var authors []string
+		for _, author := range entry.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+		rss.Channel.Item = append(rss.Channel.Item, RSSItem{
+			GUID:        entry.ID,
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: description,
+			PubDate:     pubDate,
+			Author:      strings.Join(authors, ", "),
+			Enclosures:  enclosures,
+		})
+	}
+
+	return &rss
+}
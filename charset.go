@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+
+	"golang.org/x/net/html/charset"
+)
+
+// toUTF8 converts a feed's body to UTF-8 when the Content-Type header of the response
+// declares a different charset. It reports whether the body was converted, in which
+// case the encoding named in the XML declaration must be ignored, since the HTTP
+// header takes precedence over it. Unknown charsets are ignored, leaving the XML
+// declaration to decide the encoding.
+func toUTF8(contentType string, body []byte) ([]byte, bool, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return body, false, nil
+	}
+
+	encoding, name := charset.Lookup(params["charset"])
+	if encoding == nil || name == "utf-8" {
+		return body, false, nil
+	}
+
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, false, fmt.Errorf("converting body from %v to UTF-8: %w", name, err)
+	}
+	return decoded, true, nil
+}
+
+// newXMLDecoder returns a decoder for an XML document that understands the legacy
+// encodings feeds declare in their XML declaration, such as ISO-8859-1, windows-1252
+// or Shift_JIS. If the body was already converted to UTF-8, the declared encoding is
+// ignored.
+func newXMLDecoder(body []byte, converted bool) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	if converted {
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	} else {
+		decoder.CharsetReader = charset.NewReaderLabel
+	}
+	return decoder
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// feedMediaTypes lists the media types that identify feeds in the <link> tags of
+// HTML pages.
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+}
+
+// commonFeedPaths lists paths where websites usually publish their feeds. They're
+// probed, in order, when an HTML page doesn't link to any feed.
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/rss",
+	"/feed.json",
+}
+
+// discoveredFeeds holds the feeds found by discoverFeeds. When URLs holds a single
+// feed that discoverFeeds had to fetch anyway, Feed holds it already parsed, along
+// with its caching headers, or Err holds the reason why it couldn't be fetched or
+// parsed.
+type discoveredFeeds struct {
+	URLs  []string
+	Feed  *RSSFeed
+	Cache cacheHeaders
+	Err   error
+}
+
+// discoverFeeds returns the feeds published by the website located at pageURL. If
+// pageURL points to a feed, or to anything that isn't an HTML page, it's returned as
+// is. Otherwise, discoverFeeds returns the feeds linked from the page through <link
+// rel="alternate"> tags or, if there are none, the first feed it finds probing
+// commonFeedPaths. If pageURL can't be fetched, it's returned as is along with the
+// reason, since there's no way to tell whether it's a feed.
+func (f *fetcher) discoverFeeds(ctx context.Context, pageURL string) (discoveredFeeds, error) {
+	res, body, err := f.get(ctx, pageURL, nil)
+	if err != nil {
+		return discoveredFeeds{URLs: []string{pageURL}, Err: err}, nil
+	}
+
+	// feeds are sometimes served as text/html, so the body is parsed as a feed before
+	// looking at its content type
+	feed, cache, err := decodeFeed(pageURL, res, body)
+	if err == nil || !isHTML(res.Header.Get("Content-Type"), body) {
+		return discoveredFeeds{URLs: []string{pageURL}, Feed: feed, Cache: cache, Err: err}, nil
+	}
+
+	if links := feedLinks(res.Request.URL, body); len(links) > 0 {
+		return discoveredFeeds{URLs: links}, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		candidate := res.Request.URL.ResolveReference(&url.URL{Path: path}).String()
+		res, body, err := f.get(ctx, candidate, nil)
+		if err != nil {
+			continue
+		}
+		if feed, cache, err := decodeFeed(candidate, res, body); err == nil {
+			return discoveredFeeds{URLs: []string{candidate}, Feed: feed, Cache: cache}, nil
+		}
+	}
+
+	return discoveredFeeds{}, fmt.Errorf("couldn't find any feed published by %v", pageURL)
+}
+
+// isHTML reports whether a response body is an HTML page, trusting the Content-Type
+// header when present and sniffing the body otherwise.
+func isHTML(contentType string, body []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// feedLinks returns the absolute URLs of the feeds linked from an HTML page through
+// <link rel="alternate"> tags. Relative URLs are resolved against the <base> tag of
+// the page, if any, or the URL of the page itself.
+func feedLinks(pageURL *url.URL, body []byte) []string {
+	base := pageURL
+	var links []string
+	seen := make(map[string]bool)
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attrs := make(map[string]string)
+			for _, attr := range token.Attr {
+				attrs[strings.ToLower(attr.Key)] = strings.TrimSpace(attr.Val)
+			}
+
+			switch token.Data {
+			case "base":
+				if href, err := pageURL.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+					base = href
+				}
+			case "link":
+				rels := strings.Fields(strings.ToLower(attrs["rel"]))
+				mediaType, _, _ := mime.ParseMediaType(attrs["type"])
+				if !slices.Contains(rels, "alternate") || !feedMediaTypes[mediaType] || attrs["href"] == "" {
+					continue
+				}
+				href, err := base.Parse(attrs["href"])
+				if err != nil || seen[href.String()] {
+					continue
+				}
+				seen[href.String()] = true
+				links = append(links, href.String())
+			}
+		}
+	}
+}
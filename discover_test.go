@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+const testRSSFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title>
+<item><title>First</title><link>https://example.com/first</link></item>
+</channel></rss>`
+
+func TestDiscoverFeeds(t *testing.T) {
+	pages := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"/html-feed": {"text/html; charset=utf-8", testRSSFeed},
+		"/wordpress": {"text/html", `<html><head>
+			<link rel="alternate" type="application/rss+xml" href="/feed/">
+			<link rel="alternate" type="application/json" href="/wp-json/wp/v2/posts/1">
+			</head><body></body></html>`},
+		"/several": {"text/html", `<html><head>
+			<link rel="alternate" type="application/rss+xml" href="/feed/">
+			<link rel="alternate" type="application/feed+json" href="/feed.json">
+			</head><body></body></html>`},
+		"/no-links": {"text/html", `<html><head><title>Blog</title></head><body></body></html>`},
+		"/feed":     {"application/rss+xml", testRSSFeed},
+		"/not-feed": {"text/plain", "hello"},
+	}
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", page.contentType)
+		w.Write([]byte(page.body))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path       string
+		wantURLs   []string
+		wantParsed bool
+		wantErr    bool
+	}{
+		{path: "/html-feed", wantURLs: []string{"/html-feed"}, wantParsed: true},
+		{path: "/feed", wantURLs: []string{"/feed"}, wantParsed: true},
+		{path: "/wordpress", wantURLs: []string{"/feed/"}},
+		{path: "/several", wantURLs: []string{"/feed/", "/feed.json"}},
+		{path: "/no-links", wantURLs: []string{"/feed"}, wantParsed: true},
+		{path: "/not-feed", wantURLs: []string{"/not-feed"}, wantErr: true},
+		{path: "/missing", wantURLs: []string{"/missing"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			mu.Lock()
+			clear(requests)
+			mu.Unlock()
+			found, err := newTestFetcher(t, nil).discoverFeeds(context.Background(), server.URL+tt.path)
+			if err != nil {
+				t.Fatalf("discoverFeeds() returned error: %v", err)
+			}
+
+			var want []string
+			for _, path := range tt.wantURLs {
+				want = append(want, server.URL+path)
+			}
+			if !slices.Equal(found.URLs, want) {
+				t.Errorf("discoverFeeds() found %q, want %q", found.URLs, want)
+			}
+			if parsed := found.Feed != nil; parsed != tt.wantParsed {
+				t.Errorf("discoverFeeds() parsed the feed = %v, want %v", parsed, tt.wantParsed)
+			}
+			if (found.Err != nil) != tt.wantErr {
+				t.Errorf("discoverFeeds() returned feed error %v, want error %v", found.Err, tt.wantErr)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for path, n := range requests {
+				if n > 1 {
+					t.Errorf("%v was requested %d times", path, n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// partialSuffix is appended to the name of files while they're being downloaded.
+const partialSuffix = ".part"
+
+// downloadFile downloads the file located at fileURL to filePath. Partial downloads
+// are stored next to filePath with partialSuffix appended to its name, and they're
+// resumed using range requests when the server supports them. It returns the size of
+// the downloaded file along with its SHA-256 checksum, hex encoded.
+func (f *fetcher) downloadFile(ctx context.Context, fileURL, filePath string) (int64, string, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return 0, "", fmt.Errorf("creating download directory: %w", err)
+	}
+
+	partPath := filePath + partialSuffix
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("building GET request to download file: %w", err)
+	}
+	req.Header.Set("User-Agent", "gator")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	release, err := f.limiter.acquire(ctx, req.URL.Hostname())
+	if err != nil {
+		return 0, "", fmt.Errorf("waiting to download %v: %w", fileURL, err)
+	}
+	defer release()
+
+	res, err := f.downloadClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("making GET request to download %v: %w", fileURL, err)
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case res.StatusCode == http.StatusPartialContent && contentRangeStart(res.Header.Get("Content-Range")) == offset:
+		flags |= os.O_APPEND
+	case res.StatusCode == http.StatusOK:
+		// the server doesn't support range requests, so we start over
+		flags |= os.O_TRUNC
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the partial download is either complete or corrupt; in the latter case we
+		// start over the next time
+		if offset != contentRangeSize(res.Header.Get("Content-Range")) {
+			os.Remove(partPath)
+			return 0, "", fmt.Errorf("partial download of %v doesn't match the remote file", fileURL)
+		}
+	case res.StatusCode == http.StatusPartialContent:
+		os.Remove(partPath)
+		return 0, "", fmt.Errorf("GET request to %v returned an unexpected range: %v", fileURL, res.Header.Get("Content-Range"))
+	default:
+		return 0, "", &statusError{URL: fileURL, StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	if res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		file, err := os.OpenFile(partPath, flags, 0644)
+		if err != nil {
+			return 0, "", fmt.Errorf("opening partial download: %w", err)
+		}
+		_, err = io.Copy(file, res.Body)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return 0, "", fmt.Errorf("downloading %v: %w", fileURL, err)
+		}
+	}
+
+	size, checksum, err := fileChecksum(partPath)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if err := os.Rename(partPath, filePath); err != nil {
+		return 0, "", fmt.Errorf("moving complete download in place: %w", err)
+	}
+
+	return size, checksum, nil
+}
+
+// contentRangeStart returns the first byte position of a Content-Range header like
+// "bytes 100-199/200", or -1 if it couldn't be parsed.
+func contentRangeStart(contentRange string) int64 {
+	byteRange, _, _ := strings.Cut(strings.TrimPrefix(contentRange, "bytes "), "/")
+	start, _, _ := strings.Cut(byteRange, "-")
+	n, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// contentRangeSize returns the complete length of a Content-Range header like
+// "bytes */200", or -1 if it couldn't be parsed.
+func contentRangeSize(contentRange string) int64 {
+	_, size, _ := strings.Cut(contentRange, "/")
+	n, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// fileChecksum returns the size of a file and its SHA-256 checksum, hex encoded.
+func fileChecksum(filePath string) (int64, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, "", fmt.Errorf("opening %v to compute its checksum: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("computing checksum of %v: %w", filePath, err)
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// downloadPath returns where to store a media file: a directory named after its feed
+// containing a file named after the publication date and the title of its post, along
+// with a short hash of the URL of the file, which tells apart the files attached to
+// the same post (different bitrates of an episode, for example). The extension comes
+// from the URL of the file or, if it has none, from its media type.
+func downloadPath(dir, feedName, postTitle string, publishedAt time.Time, fileURL, mediaType string) string {
+	var ext string
+	if u, err := url.Parse(fileURL); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	if ext == "" {
+		if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+
+	hash := sha256.Sum256([]byte(fileURL))
+	name := fmt.Sprintf("%v %v [%v]", publishedAt.Format(time.DateOnly), sanitizeFileName(postTitle),
+		hex.EncodeToString(hash[:4]))
+	return filepath.Join(dir, sanitizeFileName(feedName), name+ext)
+}
+
+// sanitizeFileName makes a string safe to use as a file name on any popular operating
+// system, replacing path separators and other problematic characters, and truncating
+// it to a reasonable length.
+func sanitizeFileName(name string) string {
+	const maxLength = 100
+
+	var b strings.Builder
+	length := 0
+	for _, r := range strings.TrimSpace(name) {
+		if length == maxLength {
+			break
+		}
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			r = '_'
+		}
+		b.WriteRune(r)
+		length++
+	}
+
+	sanitized := strings.Trim(b.String(), ". ")
+	if sanitized == "" {
+		return "untitled"
+	}
+	return sanitized
+}
+
+// removeDownload deletes a downloaded file along with any partial download of it. It
+// doesn't fail if the files don't exist.
+func removeDownload(filePath string) error {
+	for _, p := range []string{filePath, filePath + partialSuffix} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing %v: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadFile(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	// serveRanges answers range requests, while serveWhole ignores them
+	serveRanges := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	})
+	serveWhole := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		partial []byte
+		wantErr bool
+	}{
+		{name: "new download", handler: serveRanges},
+		{name: "resumed download", handler: serveRanges, partial: content[:4321]},
+		{name: "server ignoring ranges", handler: serveWhole, partial: []byte("garbage")},
+		{name: "complete partial download", handler: serveRanges, partial: content},
+		{name: "partial download larger than the file", handler: serveRanges, partial: append(bytes.Clone(content), 'x'), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			filePath := filepath.Join(t.TempDir(), "episode.mp3")
+			if tt.partial != nil {
+				if err := os.WriteFile(filePath+partialSuffix, tt.partial, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			size, gotChecksum, err := newTestFetcher(t, nil).downloadFile(context.Background(), server.URL+"/episode.mp3", filePath)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("downloadFile() succeeded, want error")
+				}
+				if _, err := os.Stat(filePath + partialSuffix); err == nil {
+					t.Errorf("the corrupt partial download wasn't removed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("downloadFile() returned error: %v", err)
+			}
+
+			if size != int64(len(content)) || gotChecksum != checksum {
+				t.Errorf("downloadFile() = %v, %v, want %v, %v", size, gotChecksum, len(content), checksum)
+			}
+			got, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("reading downloaded file: %v", err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloaded file doesn't match the remote one")
+			}
+			if _, err := os.Stat(filePath + partialSuffix); err == nil {
+				t.Errorf("the partial download was left behind")
+			}
+		})
+	}
+}
+
+func TestDownloadPath(t *testing.T) {
+	publishedAt := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	low := downloadPath("/downloads", "My/Podcast", "Episode 1: Pilot", publishedAt,
+		"https://cdn.example.com/ep1-64k.mp3", "audio/mpeg")
+	high := downloadPath("/downloads", "My/Podcast", "Episode 1: Pilot", publishedAt,
+		"https://cdn.example.com/ep1-128k.mp3", "audio/mpeg")
+
+	if low == high {
+		t.Errorf("files attached to the same post share the path %v", low)
+	}
+	if dir := filepath.Dir(low); dir != filepath.Join("/downloads", "My_Podcast") {
+		t.Errorf("file stored in %v, want %v", dir, filepath.Join("/downloads", "My_Podcast"))
+	}
+	if name := filepath.Base(low); !strings.HasPrefix(name, "2024-03-10 Episode 1_ Pilot [") || !strings.HasSuffix(name, "].mp3") {
+		t.Errorf("file named %q, want a name made of the date, the title and a hash", name)
+	}
+}
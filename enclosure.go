@@ -0,0 +1,156 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"gator/internal/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RSSEnclosure is the <enclosure> element of an RSS item, which links the item to a
+// media file. It's also used to represent Atom links with relation "enclosure".
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// MediaContent is the <media:content> element of the Media RSS module. It's also used
+// to represent the attachments of JSON feed items.
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+// enclosure is a media file attached to a post, as stored in the database. Unknown
+// sizes, durations and episode numbers are zero.
+type enclosure struct {
+	URL       string
+	MediaType string
+	// Size is expressed in bytes and Duration in seconds
+	Size     int64
+	Duration int32
+	Episode  int32
+}
+
+// enclosures merges the <enclosure> and <media:content> elements of the item, along
+// with its iTunes podcast metadata, in a single list of media files. Files linked
+// from both elements are listed once.
+func (r *RSSItem) enclosures() []enclosure {
+	duration := parseMediaDuration(r.ITunesDuration)
+	episode, _ := strconv.Atoi(strings.TrimSpace(r.ITunesEpisode))
+
+	var enclosures []enclosure
+	seen := make(map[string]int)
+	add := func(e enclosure) {
+		if e.URL == "" {
+			return
+		}
+		if i, ok := seen[e.URL]; ok {
+			// keep the details missing from the first element listing the file
+			enclosures[i].MediaType = cmp.Or(enclosures[i].MediaType, e.MediaType)
+			enclosures[i].Size = cmp.Or(enclosures[i].Size, e.Size)
+			enclosures[i].Duration = cmp.Or(enclosures[i].Duration, e.Duration)
+			return
+		}
+		seen[e.URL] = len(enclosures)
+		enclosures = append(enclosures, e)
+	}
+
+	// the iTunes metadata describes the episode, so it doesn't apply to files like
+	// the cover art
+	withEpisode := func(e enclosure) enclosure {
+		if isPlayable(e.MediaType) {
+			e.Duration = cmp.Or(e.Duration, duration)
+			e.Episode = int32(episode)
+		}
+		return e
+	}
+
+	for _, e := range r.Enclosures {
+		size, _ := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+		add(withEpisode(enclosure{
+			URL:       strings.TrimSpace(e.URL),
+			MediaType: strings.TrimSpace(e.Type),
+			Size:      size,
+		}))
+	}
+	for _, m := range r.MediaContent {
+		size, _ := strconv.ParseInt(strings.TrimSpace(m.FileSize), 10, 64)
+		mediaType := strings.TrimSpace(m.Type)
+		if mediaType == "" {
+			mediaType = strings.TrimSpace(m.Medium)
+		}
+		add(withEpisode(enclosure{
+			URL:       strings.TrimSpace(m.URL),
+			MediaType: mediaType,
+			Size:      size,
+			Duration:  parseMediaDuration(m.Duration),
+		}))
+	}
+
+	return enclosures
+}
+
+// isPlayable reports whether a media type (or a Media RSS medium) denotes audio or
+// video. Unknown media types are assumed to be playable.
+func isPlayable(mediaType string) bool {
+	return mediaType == "" || mediaType == "audio" || mediaType == "video" ||
+		strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/")
+}
+
+// parseMediaDuration parses the duration of a media file expressed in seconds or in
+// the "HH:MM:SS" and "MM:SS" formats allowed by iTunes. It returns the duration in
+// seconds, or zero if it couldn't be parsed.
+func parseMediaDuration(duration string) int32 {
+	duration = strings.TrimSpace(duration)
+	if duration == "" {
+		return 0
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(duration, ":") {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value < 0 {
+			return 0
+		}
+		seconds = seconds*60 + value
+	}
+
+	return int32(seconds)
+}
+
+// formatEnclosure describes an enclosure in a single line, including its media type,
+// size and duration when known.
+func formatEnclosure(e database.Enclosure) string {
+	details := []string{e.MediaType}
+	if e.Size.Valid {
+		details = append(details, formatSize(e.Size.Int64))
+	}
+	if e.Duration.Valid {
+		details = append(details, (time.Duration(e.Duration.Int32) * time.Second).String())
+	}
+	if e.Episode.Valid {
+		details = append(details, fmt.Sprintf("episode %v", e.Episode.Int32))
+	}
+	return fmt.Sprintf("[%v] %v", strings.Join(details, ", "), e.Url)
+}
+
+// formatSize formats a size in bytes using binary prefixes.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%v B", size)
+	}
+	value, prefix := float64(size), 0
+	for value >= unit && prefix < len("KMGTPE") {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTPE"[prefix-1])
+}
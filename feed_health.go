@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"time"
+)
+
+// feedBackoffBase and feedBackoffMax bound the time a failing feed has to wait before
+// being fetched again. The wait doubles with every consecutive failure.
+const (
+	feedBackoffBase = 5 * time.Minute
+	feedBackoffMax  = 24 * time.Hour
+)
+
+// feedBackoff returns how long to wait before fetching again a feed that failed
+// `failures` consecutive times.
+func feedBackoff(failures int32) time.Duration {
+	backoff := feedBackoffBase
+	for i := int32(1); i < failures && backoff < feedBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, feedBackoffMax)
+}
+
+// retryWait returns how long to wait before fetching again a feed that failed
+// `failures` consecutive times with err. The server may ask us to wait even longer
+// through the Retry-After header, which is honored up to feedBackoffMax, lest a
+// misconfigured server stops the feed from being fetched for good.
+func retryWait(failures int32, err error) time.Duration {
+	wait := feedBackoff(failures)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		wait = max(wait, min(statusErr.RetryAfter, feedBackoffMax))
+	}
+	return wait
+}
+
+// feedHealth summarizes the outcome of the latest fetches of a feed in a short,
+// human readable string.
+func feedHealth(feed database.GetFeedsRow) string {
+	switch {
+	case feed.DisabledAt.Valid:
+		return fmt.Sprintf("disabled since %v: %v", formatNullTime(feed.DisabledAt), feed.DisabledReason.String)
+	case !feed.LastFetchedAt.Valid:
+		return "never fetched"
+	case feed.ConsecutiveFailures == 0:
+		return fmt.Sprintf("ok (last success %v)", formatNullTime(feed.LastSucceededAt))
+	default:
+		return fmt.Sprintf("failing %v time(s) (last success %v): %v",
+			feed.ConsecutiveFailures, formatNullTime(feed.LastSucceededAt), feed.LastError.String)
+	}
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return "never"
+	}
+	return t.Time.Format(time.DateTime)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		err      error
+		want     time.Duration
+	}{
+		{name: "first failure", failures: 1, err: errors.New("timeout"), want: feedBackoffBase},
+		{name: "third failure", failures: 3, err: errors.New("timeout"), want: 4 * feedBackoffBase},
+		{name: "many failures", failures: 40, err: errors.New("timeout"), want: feedBackoffMax},
+		{
+			name:     "shorter Retry-After",
+			failures: 3,
+			err:      &statusError{StatusCode: 503, RetryAfter: time.Minute},
+			want:     4 * feedBackoffBase,
+		},
+		{
+			name:     "longer Retry-After",
+			failures: 1,
+			err:      fmt.Errorf("fetching: %w", &statusError{StatusCode: 429, RetryAfter: 2 * time.Hour}),
+			want:     2 * time.Hour,
+		},
+		{
+			name:     "Retry-After beyond the maximum",
+			failures: 1,
+			err:      &statusError{StatusCode: 503, RetryAfter: 365 * 24 * time.Hour},
+			want:     feedBackoffMax,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryWait(tt.failures, tt.err); got != tt.want {
+				t.Errorf("retryWait(%v, %v) = %v, want %v", tt.failures, tt.err, got, tt.want)
+			}
+		})
+	}
+}
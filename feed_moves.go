@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// moveFeed updates the URL of a feed that was permanently moved to newURL and returns
+// the ID of the feed that lives at newURL afterwards. Since URLs are unique, if
+// another feed already lives at newURL, the moved feed is merged into it: its
+// followers and the posts the other feed doesn't have are handed over, along with
+// the request settings the other feed lacks, and the moved feed is deleted. The media
+// files downloaded for the posts both feeds have are handed over too when the other
+// feed didn't download them, and removed otherwise.
+func moveFeed(ctx context.Context, s *state, feedID uuid.UUID, newURL string) (uuid.UUID, error) {
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	var orphans []string
+	existingID, err := qtx.GetFeedIdByURL(ctx, newURL)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if err := qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			Url:       newURL,
+			UpdatedAt: time.Now().UTC(),
+			ID:        feedID,
+		}); err != nil {
+			return uuid.Nil, fmt.Errorf("updating feed URL: %w", err)
+		}
+		existingID = feedID
+	case err != nil:
+		return uuid.Nil, fmt.Errorf("looking for a feed at the new URL: %w", err)
+	default:
+		if err := qtx.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+			ToFeedID:   existingID,
+			FromFeedID: feedID,
+		}); err != nil {
+			return uuid.Nil, fmt.Errorf("moving feed followers: %w", err)
+		}
+		if err := qtx.MovePosts(ctx, database.MovePostsParams{
+			ToFeedID:   existingID,
+			FromFeedID: feedID,
+		}); err != nil {
+			return uuid.Nil, fmt.Errorf("moving feed posts: %w", err)
+		}
+		if err := qtx.CopyFeedRequestSettings(ctx, database.CopyFeedRequestSettingsParams{
+			UpdatedAt:  time.Now().UTC(),
+			ToFeedID:   existingID,
+			FromFeedID: feedID,
+		}); err != nil {
+			return uuid.Nil, fmt.Errorf("copying feed request settings: %w", err)
+		}
+		// the posts left behind are the ones both feeds have, which are deleted along
+		// with the moved feed, so their downloads must be dealt with beforehand
+		if err := qtx.MoveDownloads(ctx, database.MoveDownloadsParams{
+			UpdatedAt:  time.Now().UTC(),
+			FromFeedID: feedID,
+			ToFeedID:   existingID,
+		}); err != nil {
+			return uuid.Nil, fmt.Errorf("moving feed downloads: %w", err)
+		}
+		orphans, err = qtx.DeleteFeedDownloads(ctx, feedID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("deleting feed downloads: %w", err)
+		}
+		if err := qtx.DeleteFeed(ctx, feedID); err != nil {
+			return uuid.Nil, fmt.Errorf("deleting merged feed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	// files are only removed once the records are gone for good; failing to remove
+	// them doesn't undo the move
+	for _, orphan := range orphans {
+		if err := removeDownload(orphan); err != nil {
+			log.Printf("[WARN] %v\n", err)
+		}
+	}
+
+	return existingID, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"gator/internal/config"
+	"gator/internal/database"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMoveFeedIntoExistingFeed(t *testing.T) {
+	db, queries, user := testDatabase(t)
+	var ids []uuid.UUID
+	for id := range createTestFeeds(t, queries, user, 2) {
+		ids = append(ids, id)
+	}
+	movedID, targetID := ids[0], ids[1]
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if err := queries.UpdateFeedRequestSettings(ctx, database.UpdateFeedRequestSettingsParams{
+		UserAgent:      sql.NullString{String: "gator-test", Valid: true},
+		RequestHeaders: []string{"X-Token: secret"},
+		UpdatedAt:      now,
+		ID:             movedID,
+	}); err != nil {
+		t.Fatalf("UpdateFeedRequestSettings() returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	// store stores the items in a feed, along with a download for every enclosure
+	// listed in downloaded, and returns the path of the downloaded files
+	store := func(feedID uuid.UUID, items []RSSItem, downloaded ...string) map[string]string {
+		t.Helper()
+		posts := postsBatch(feedID, now, items)
+		if _, err := queries.UpsertPosts(ctx, posts); err != nil {
+			t.Fatalf("UpsertPosts() returned error: %v", err)
+		}
+		postIDs, err := queries.GetPostIDsByGUID(ctx, database.GetPostIDsByGUIDParams{FeedID: feedID, Guids: posts.Guids})
+		if err != nil {
+			t.Fatalf("GetPostIDsByGUID() returned error: %v", err)
+		}
+		if err := queries.UpsertEnclosures(ctx, enclosuresBatch(now, postIDs, items)); err != nil {
+			t.Fatalf("UpsertEnclosures() returned error: %v", err)
+		}
+
+		paths := make(map[string]string)
+		for _, post := range postIDs {
+			enclosures, err := queries.GetEnclosuresForPost(ctx, post.ID)
+			if err != nil {
+				t.Fatalf("GetEnclosuresForPost() returned error: %v", err)
+			}
+			for _, enclosure := range enclosures {
+				for _, url := range downloaded {
+					if enclosure.Url != url {
+						continue
+					}
+					path := filepath.Join(dir, uuid.NewString()+".mp3")
+					if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+						t.Fatal(err)
+					}
+					if err := queries.CreateDownload(ctx, database.CreateDownloadParams{
+						ID: uuid.New(), CreatedAt: now, UpdatedAt: now, EnclosureID: enclosure.ID,
+						Path: path, Size: 5, Sha256: "test",
+					}); err != nil {
+						t.Fatalf("CreateDownload() returned error: %v", err)
+					}
+					paths[url] = path
+				}
+			}
+		}
+		return paths
+	}
+	episode := func(n string) RSSItem {
+		return RSSItem{
+			Title: "Episode " + n, Link: "https://example.com/" + n, GUID: "episode-" + n,
+			Enclosures: []RSSEnclosure{{URL: "https://cdn.example.com/" + n + ".mp3", Type: "audio/mpeg"}},
+		}
+	}
+
+	// both feeds have episodes 1 and 2, but only the moved one has episode 3; episode
+	// 1 was only downloaded through the moved feed, episode 2 through both of them
+	movedFiles := store(movedID, []RSSItem{episode("1"), episode("2"), episode("3")},
+		"https://cdn.example.com/1.mp3", "https://cdn.example.com/2.mp3", "https://cdn.example.com/3.mp3")
+	targetFiles := store(targetID, []RSSItem{episode("1"), episode("2")}, "https://cdn.example.com/2.mp3")
+
+	var targetURL string
+	if err := db.QueryRowContext(ctx, "SELECT url FROM feeds WHERE id = $1", targetID).Scan(&targetURL); err != nil {
+		t.Fatalf("getting URL of target feed: %v", err)
+	}
+	s := &state{db: queries, sqlDB: db, cfg: &config.Config{}}
+	feedID, err := moveFeed(ctx, s, movedID, targetURL)
+	if err != nil || feedID != targetID {
+		t.Fatalf("moveFeed() = %v, %v, want %v", feedID, err, targetID)
+	}
+
+	var userAgent sql.NullString
+	var headers int
+	if err := db.QueryRowContext(ctx, "SELECT user_agent, cardinality(request_headers) FROM feeds WHERE id = $1", targetID).Scan(&userAgent, &headers); err != nil {
+		t.Fatalf("getting request settings of target feed: %v", err)
+	}
+	if userAgent.String != "gator-test" || headers != 1 {
+		t.Errorf("target feed has user agent %q and %d header(s), want the ones of the moved feed", userAgent.String, headers)
+	}
+
+	// the downloads of episodes 1 and 3 now belong to the target feed, while the
+	// duplicate download of episode 2 is gone along with its file
+	downloads := make(map[string]bool)
+	rows, err := db.QueryContext(ctx, `SELECT downloads.path FROM downloads
+		INNER JOIN enclosures ON downloads.enclosure_id = enclosures.id
+		INNER JOIN posts ON enclosures.post_id = posts.id
+		WHERE posts.feed_id = $1`, targetID)
+	if err != nil {
+		t.Fatalf("getting downloads of target feed: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			t.Fatal(err)
+		}
+		downloads[path] = true
+	}
+	for _, path := range []string{
+		movedFiles["https://cdn.example.com/1.mp3"],
+		movedFiles["https://cdn.example.com/3.mp3"],
+		targetFiles["https://cdn.example.com/2.mp3"],
+	} {
+		if !downloads[path] {
+			t.Errorf("target feed has no download stored at %v", path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("downloaded file was removed: %v", err)
+		}
+	}
+	if len(downloads) != 3 {
+		t.Errorf("target feed has %d downloads, want 3", len(downloads))
+	}
+	if _, err := os.Stat(movedFiles["https://cdn.example.com/2.mp3"]); !os.IsNotExist(err) {
+		t.Errorf("duplicate download wasn't removed: %v", err)
+	}
+}
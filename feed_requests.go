@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"gator/internal/config"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// secretPrefix marks header values that refer to a secret in the secrets file instead
+// of holding the value itself, as in "Private-Token: secret:gitlab".
+const secretPrefix = "secret:"
+
+// feedRequestSettings holds the settings of the requests made to fetch a feed. Headers
+// are stored as "Name: value" lines, and the password used for Basic authentication
+// is the name of a secret, never the password itself.
+type feedRequestSettings struct {
+	UserAgent      string
+	Headers        []string
+	Username       string
+	PasswordSecret string
+}
+
+// header builds the headers to send when fetching the feed, resolving the secrets
+// they refer to. Basic authentication credentials take precedence over an
+// Authorization header, and a User-Agent header over the user agent override.
+func (r feedRequestSettings) header(cfg *config.Config) (http.Header, error) {
+	header := http.Header{}
+	if r.UserAgent != "" {
+		header.Set("User-Agent", r.UserAgent)
+	}
+
+	for _, line := range r.Headers {
+		name, value, err := parseHeaderLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if secretName, ok := strings.CutPrefix(value, secretPrefix); ok {
+			value, err = cfg.Secret(secretName)
+			if err != nil {
+				return nil, fmt.Errorf("resolving value of header %v: %w", name, err)
+			}
+		}
+		header.Set(name, value)
+	}
+
+	if r.Username != "" {
+		password, err := cfg.Secret(r.PasswordSecret)
+		if err != nil {
+			return nil, fmt.Errorf("resolving password of user %v: %w", r.Username, err)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(r.Username + ":" + password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+
+	return header, nil
+}
+
+// parseHeaderLine splits a "Name: value" line into the name and the value of the
+// header, making sure both are valid and that headers carrying credentials refer to a
+// secret instead of holding it.
+func parseHeaderLine(line string) (string, string, error) {
+	name, value, found := strings.Cut(line, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !found || !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+		return "", "", fmt.Errorf("invalid header %q, expected \"Name: value\"", line)
+	}
+	name = http.CanonicalHeaderKey(name)
+	if isCredentialHeader(name) && !strings.HasPrefix(value, secretPrefix) {
+		return "", "", fmt.Errorf("header %v carries credentials, so its value must refer to a secret as in \"%v: %v<name>\"",
+			name, name, secretPrefix)
+	}
+	return name, value, nil
+}
+
+// isCredentialHeader reports whether the header called name (in canonical form) is
+// meant to carry credentials, like Authorization, Cookie or X-Api-Key.
+func isCredentialHeader(name string) bool {
+	switch name {
+	case "Authorization", "Proxy-Authorization", "Cookie":
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{"token", "key", "secret", "password", "auth", "session"} {
+		if lower == suffix || strings.HasSuffix(lower, "-"+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestParseHeaderLine(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{line: "Accept: application/rss+xml", wantName: "Accept", wantValue: "application/rss+xml"},
+		{line: "  x-custom-header :  value ", wantName: "X-Custom-Header", wantValue: "value"},
+		{line: "Private-Token: secret:gitlab", wantName: "Private-Token", wantValue: "secret:gitlab"},
+		{line: "authorization: secret:bearer", wantName: "Authorization", wantValue: "secret:bearer"},
+		{line: "no colon", wantErr: true},
+		{line: "Bad Name: value", wantErr: true},
+		{line: "Authorization: Bearer abc", wantErr: true},
+		{line: "Cookie: session=abc", wantErr: true},
+		{line: "Private-Token: glpat-abc", wantErr: true},
+		{line: "X-Api-Key: abc", wantErr: true},
+		{line: "X-Auth-Token: abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			name, value, err := parseHeaderLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHeaderLine(%q) error = %v, want error %v", tt.line, err, tt.wantErr)
+			}
+			if name != tt.wantName || value != tt.wantValue {
+				t.Errorf("parseHeaderLine(%q) = %q, %q, want %q, %q", tt.line, name, value, tt.wantName, tt.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"gator/internal/config"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// fetcher makes the HTTP requests gator needs to fetch feeds, discover them and
+// download their media files. Feeds and web pages are fetched with a client bound by
+// the configured timeouts and maximum body size, while downloads only share its
+// connection settings, since media files can be large and slow to transfer. Every
+// request goes through the host limiter first.
+type fetcher struct {
+	client         *http.Client
+	downloadClient *http.Client
+	maxBodySize    int64
+	limiter        *hostLimiter
+}
+
+func newFetcher(settings config.FetchSettings) (*fetcher, error) {
+	tlsConfig, err := newTLSConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: settings.ConnectTimeout}).DialContext
+	transport.TLSHandshakeTimeout = settings.ConnectTimeout
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxyFunc(settings.Proxy, settings.NoProxy)
+	// compressed responses are decoded by readBody, which also understands brotli
+	transport.DisableCompression = true
+
+	checkRedirect := func(req *http.Request, via []*http.Request) error {
+		if len(via) > settings.MaxRedirects {
+			return &tooManyRedirectsError{URL: via[0].URL.String(), Max: settings.MaxRedirects}
+		}
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			// the headers set for a feed may hold credentials, which must only be
+			// sent to the host they were meant for
+			for key := range req.Header {
+				if !forwardedHeaders[key] {
+					req.Header.Del(key)
+				}
+			}
+		}
+		return nil
+	}
+
+	return &fetcher{
+		client: &http.Client{
+			Transport:     transport,
+			CheckRedirect: checkRedirect,
+			Timeout:       settings.Timeout,
+		},
+		downloadClient: &http.Client{
+			Transport:     transport,
+			CheckRedirect: checkRedirect,
+		},
+		maxBodySize: settings.MaxBodySize,
+		limiter:     newHostLimiter(settings.Hosts, settings.PerHost),
+	}, nil
+}
+
+// forwardedHeaders lists the headers kept when a request is redirected to another
+// host. They're the ones gator sets itself, while any other header comes from the
+// settings of a feed.
+var forwardedHeaders = map[string]bool{
+	"Accept-Encoding":   true,
+	"If-Modified-Since": true,
+	"If-None-Match":     true,
+	"If-Range":          true,
+	"Range":             true,
+	"User-Agent":        true,
+}
+
+// newTLSConfig returns the TLS configuration used to connect to servers, which trusts
+// the configured certificate authorities on top of the system ones and presents the
+// configured client certificate to servers asking for one.
+func newTLSConfig(settings config.FetchSettings) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if settings.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		bundle, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %v", settings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if settings.ClientCertFile != "" {
+		// the key may come in the same file as the certificate
+		keyFile := cmp.Or(settings.ClientKeyFile, settings.ClientCertFile)
+		cert, err := tls.LoadX509KeyPair(settings.ClientCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// proxyFunc returns the function that picks the proxy each request goes through:
+// proxy if it's not nil, or the one set in the environment otherwise. Requests to
+// the hosts and feed URLs listed in noProxy don't go through any proxy.
+func proxyFunc(proxy *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		if proxy == nil {
+			return http.ProxyFromEnvironment(req)
+		}
+		return proxy, nil
+	}
+}
+
+// bypassProxy reports whether requests to u must skip the proxy, which is the case
+// when u is one of the URLs in noProxy or its host is one of the hosts (or a
+// subdomain of one of the domains) in it.
+func bypassProxy(u *url.URL, noProxy []string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, entry := range noProxy {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "://") {
+			if entry == u.String() {
+				return true
+			}
+			continue
+		}
+		domain := strings.TrimPrefix(strings.ToLower(entry), ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// statusError is returned when a server answers a request with an unexpected status.
+// RetryAfter holds the time the server asked us to wait before trying again, if any.
+type statusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("GET request to %v returned status %q", e.URL, e.Status)
+}
+
+// timeoutError is returned when a request isn't completed within the configured
+// timeouts.
+type timeoutError struct {
+	URL string
+	Err error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("GET request to %v timed out: %v", e.URL, e.Err)
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.Err
+}
+
+// bodyTooLargeError is returned when a response body exceeds the configured limit.
+type bodyTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *bodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body of GET request to %v exceeds %v", e.URL, formatSize(e.Limit))
+}
+
+// tooManyRedirectsError is returned when a request is redirected more times than
+// allowed.
+type tooManyRedirectsError struct {
+	URL string
+	Max int
+}
+
+func (e *tooManyRedirectsError) Error() string {
+	return fmt.Sprintf("GET request to %v was redirected more than %d times", e.URL, e.Max)
+}
+
+// contentEncodingError is returned when a response body is compressed with an
+// unsupported algorithm or can't be decompressed.
+type contentEncodingError struct {
+	URL      string
+	Encoding string
+	Err      error
+}
+
+func (e *contentEncodingError) Error() string {
+	return fmt.Sprintf("decoding %q response body of GET request to %v: %v", e.Encoding, e.URL, e.Err)
+}
+
+func (e *contentEncodingError) Unwrap() error {
+	return e.Err
+}
+
+// get makes a GET request to rawURL, adding the given headers to it (which may
+// override the default User-Agent), and returns the response along with its
+// decompressed body. Responses with a non-2xx status result in a statusError, except
+// for 304 Not Modified, which is returned with an empty body so callers making
+// conditional requests can handle it.
+func (f *fetcher) get(ctx context.Context, rawURL string, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building GET request to %v: %w", rawURL, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "gator")
+	}
+	req.Header.Set("Accept-Encoding", "gzip, br")
+
+	release, err := f.limiter.acquire(ctx, req.URL.Hostname())
+	if err != nil {
+		return nil, nil, fmt.Errorf("waiting to make GET request to %v: %w", rawURL, err)
+	}
+	defer release()
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, classifyError(ctx, rawURL, fmt.Errorf("making GET request to %v: %w", rawURL, err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		return res, nil, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		statusErr := &statusError{URL: rawURL, StatusCode: res.StatusCode, Status: res.Status}
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+			statusErr.RetryAfter = retryAfter(res.Header.Get("Retry-After"), time.Now())
+		}
+		return res, nil, statusErr
+	}
+
+	body, err := f.readBody(rawURL, res)
+	if err != nil {
+		return nil, nil, classifyError(ctx, rawURL, err)
+	}
+
+	return res, body, nil
+}
+
+// readBody reads and decompresses the body of a response, making sure it doesn't
+// exceed the maximum body size.
+func (f *fetcher) readBody(rawURL string, res *http.Response) ([]byte, error) {
+	if res.ContentLength > f.maxBodySize {
+		return nil, &bodyTooLargeError{URL: rawURL, Limit: f.maxBodySize}
+	}
+
+	var reader io.Reader = res.Body
+	encoding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, &contentEncodingError{URL: rawURL, Encoding: encoding, Err: err}
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case "br":
+		reader = brotli.NewReader(res.Body)
+	default:
+		return nil, &contentEncodingError{URL: rawURL, Encoding: encoding, Err: errors.New("unsupported encoding")}
+	}
+
+	// one extra byte tells bodies that reach the limit apart from those exceeding it
+	body, err := io.ReadAll(io.LimitReader(reader, f.maxBodySize+1))
+	if err != nil {
+		// errors that don't come from the connection come from the decompressor
+		var netErr net.Error
+		if reader != res.Body && res.Request.Context().Err() == nil && !errors.As(err, &netErr) {
+			return nil, &contentEncodingError{URL: rawURL, Encoding: encoding, Err: err}
+		}
+		return nil, fmt.Errorf("reading response body of GET request to %v: %w", rawURL, err)
+	}
+	if int64(len(body)) > f.maxBodySize {
+		return nil, &bodyTooLargeError{URL: rawURL, Limit: f.maxBodySize}
+	}
+
+	return body, nil
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a number of
+// seconds or an HTTP date, into the time left to wait from now.
+func retryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// permanentURL returns the URL a request was permanently moved to, following the
+// chain of redirects that led to res as long as they're permanent (301 Moved
+// Permanently or 308 Permanent Redirect). It returns an empty string if the first
+// redirect wasn't permanent or there were no redirects at all.
+func permanentURL(res *http.Response) string {
+	var chain []*http.Request
+	for req := res.Request; req != nil; req = req.Response.Request {
+		chain = append(chain, req)
+		if req.Response == nil {
+			break
+		}
+	}
+	slices.Reverse(chain)
+
+	movedTo := ""
+	for _, req := range chain[1:] {
+		code := req.Response.StatusCode
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			break
+		}
+		movedTo = req.URL.String()
+	}
+	return movedTo
+}
+
+// classifyError wraps err in a timeoutError when it was caused by one of the
+// configured timeouts. Errors caused by the cancellation of ctx are returned as is.
+func classifyError(ctx context.Context, rawURL string, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &timeoutError{URL: rawURL, Err: err}
+	}
+	return err
+}
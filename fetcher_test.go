@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"gator/internal/config"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestFetcher returns a fetcher with the default settings, changed by the given
+// function if it's not nil.
+func newTestFetcher(t *testing.T, change func(*config.FetchSettings)) *fetcher {
+	t.Helper()
+	settings := config.FetchSettings{
+		ConnectTimeout: 5 * time.Second,
+		Timeout:        10 * time.Second,
+		MaxBodySize:    1 << 20,
+		MaxRedirects:   5,
+		Hosts:          config.HostSettings{Concurrency: 4},
+		// requests to the test servers must never go through a proxy set in the
+		// environment
+		NoProxy: []string{"127.0.0.1"},
+	}
+	if change != nil {
+		change(&settings)
+	}
+	f, err := newFetcher(settings)
+	if err != nil {
+		t.Fatalf("newFetcher() returned error: %v", err)
+	}
+	return f
+}
+
+func TestRedirectDropsFeedHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer other.Close()
+
+	tests := []struct {
+		name     string
+		redirect string
+		kept     bool
+	}{
+		{name: "same host", redirect: "/feed", kept: true},
+		{name: "other host", redirect: other.URL + "/feed", kept: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/feed" {
+					received <- r.Header.Clone()
+					return
+				}
+				http.Redirect(w, r, tt.redirect, http.StatusFound)
+			}))
+			defer origin.Close()
+
+			header := http.Header{}
+			header.Set("Private-Token", "hunter2")
+			header.Set("User-Agent", "custom agent")
+			if _, _, err := newTestFetcher(t, nil).get(context.Background(), origin.URL+"/old", header); err != nil {
+				t.Fatalf("get() returned error: %v", err)
+			}
+
+			got := <-received
+			if kept := got.Get("Private-Token") != ""; kept != tt.kept {
+				t.Errorf("Private-Token sent after redirect = %v, want %v", kept, tt.kept)
+			}
+			if agent := got.Get("User-Agent"); agent != "custom agent" {
+				t.Errorf("User-Agent after redirect = %q, want %q", agent, "custom agent")
+			}
+		})
+	}
+}
+
+// writePEM writes the given PEM blocks to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFetchWithCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := writePEM(t, t.TempDir(), "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	if _, _, err := newTestFetcher(t, nil).get(context.Background(), server.URL, nil); err == nil {
+		t.Errorf("get() trusted a certificate signed by an unknown authority")
+	}
+
+	f := newTestFetcher(t, func(settings *config.FetchSettings) { settings.CAFile = caFile })
+	if _, _, err := f.get(context.Background(), server.URL, nil); err != nil {
+		t.Errorf("get() returned error: %v", err)
+	}
+}
+
+func TestFetchWithClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	certBlock := &pem.Block{Type: "CERTIFICATE", Bytes: der}
+	keyBlock := &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		wantErr  bool
+	}{
+		{name: "no certificate", wantErr: true},
+		{name: "separate files", certFile: writePEM(t, dir, "cert.pem", certBlock), keyFile: writePEM(t, dir, "key.pem", keyBlock)},
+		{name: "single file", certFile: writePEM(t, dir, "both.pem", certBlock, keyBlock)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFetcher(t, func(settings *config.FetchSettings) {
+				settings.CAFile = caFile
+				settings.ClientCertFile = tt.certFile
+				settings.ClientKeyFile = tt.keyFile
+			})
+			_, _, err := f.get(context.Background(), server.URL, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("get() returned error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"intranet.example.com", ".corp.example", "https://example.org/private/feed.xml"}
+	tests := []struct {
+		rawURL string
+		want   bool
+	}{
+		{rawURL: "https://intranet.example.com/feed", want: true},
+		{rawURL: "https://blog.intranet.example.com/feed", want: true},
+		{rawURL: "https://INTRANET.example.com:8443/feed", want: true},
+		{rawURL: "https://wiki.corp.example/feed", want: true},
+		{rawURL: "https://example.org/private/feed.xml", want: true},
+		{rawURL: "https://example.org/public/feed.xml", want: false},
+		{rawURL: "https://example.com/feed", want: false},
+		{rawURL: "https://notintranet.example.com/feed", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rawURL, func(t *testing.T) {
+			u, err := url.Parse(tt.rawURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bypassProxy(u, noProxy); got != tt.want {
+				t.Errorf("bypassProxy(%v) = %v, want %v", tt.rawURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchThroughProxy(t *testing.T) {
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer direct.Close()
+
+	f := newTestFetcher(t, func(settings *config.FetchSettings) {
+		settings.Proxy = proxyURL
+		settings.NoProxy = []string{direct.URL + "/rss"}
+	})
+	if _, _, err := f.get(context.Background(), "http://feeds.example/rss", nil); err != nil {
+		t.Fatalf("get() returned error: %v", err)
+	}
+	if got := <-proxied; got != "http://feeds.example/rss" {
+		t.Errorf("proxy received a request for %v, want http://feeds.example/rss", got)
+	}
+
+	// feeds listed in no_proxy are fetched directly
+	if _, _, err := f.get(context.Background(), direct.URL+"/rss", nil); err != nil {
+		t.Fatalf("get() returned error: %v", err)
+	}
+	select {
+	case got := <-proxied:
+		t.Errorf("proxy received a request for %v, which is listed in no_proxy", got)
+	default:
+	}
+}
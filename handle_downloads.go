@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// handlerDownload downloads the media files (podcast episodes, for example) attached
+// to the latest posts of the feeds followed by the user. The files are stored in the
+// directory set in the configuration file, and the command keeps only the configured
+// number of episodes per feed, removing older downloads. Interrupted downloads are
+// resumed the next time the command runs.
+//
+// It optionally takes the URL of a followed feed to download only its files.
+//
+// It returns a non-nil error if there was a problem querying the database or the user
+// made a mistake when calling the command. Failed downloads are logged and retried
+// the next time the command runs.
+func handlerDownload(s *state, cmd command, userData database.User) error {
+	if len(cmd.arguments) > 1 {
+		return fmt.Errorf("usage: %v [feed URL]", cmd.name)
+	}
+
+	dir, err := s.cfg.DownloadDirectory()
+	if err != nil {
+		return fmt.Errorf("getting download directory: %w", err)
+	}
+
+	ctx := context.Background()
+	enclosures, err := s.db.GetEnclosuresToDownload(ctx, userData.ID)
+	if err != nil {
+		return fmt.Errorf("getting media files from the database: %w", err)
+	}
+
+	feedURL := ""
+	if len(cmd.arguments) == 1 {
+		feedURL = cmd.arguments[0]
+	}
+	toDownload, toRemove := planDownloads(enclosures, feedURL, s.cfg.EpisodesToKeep)
+
+	for _, enclosure := range toRemove {
+		if err := removeDownload(enclosure.DownloadPath.String); err != nil {
+			log.Printf("[NOT OK] %v", err)
+			continue
+		}
+		if err := s.db.DeleteDownload(ctx, enclosure.EnclosureID); err != nil {
+			return fmt.Errorf("deleting download record from the database: %w", err)
+		}
+		fmt.Printf("removed %q\n", enclosure.DownloadPath.String)
+	}
+
+	for _, enclosure := range toDownload {
+		filePath := downloadPath(dir, enclosure.FeedName, enclosure.PostTitle,
+			enclosure.PublishedAt, enclosure.EnclosureUrl, enclosure.MediaType)
+		size, checksum, err := s.fetcher.downloadFile(ctx, enclosure.EnclosureUrl, filePath)
+		if err != nil {
+			log.Printf("[NOT OK] downloading %q from %q: %v", enclosure.PostTitle, enclosure.FeedName, err)
+			continue
+		}
+
+		timestamp := time.Now().UTC()
+		if err := s.db.CreateDownload(ctx, database.CreateDownloadParams{
+			ID:          uuid.New(),
+			CreatedAt:   timestamp,
+			UpdatedAt:   timestamp,
+			EnclosureID: enclosure.EnclosureID,
+			Path:        filePath,
+			Size:        size,
+			Sha256:      checksum,
+		}); err != nil {
+			return fmt.Errorf("storing download record in the database: %w", err)
+		}
+		fmt.Printf("downloaded %q (%v, SHA-256 %v)\n", filePath, formatSize(size), checksum)
+	}
+
+	return nil
+}
+
+// planDownloads picks, among the media files attached to the posts of the feeds
+// followed by the user, the ones to download and the downloaded ones to remove. For
+// each feed (or only the one at feedURL, if it's not empty), the playable files of
+// the latest posts are kept, up to the number of episodes returned by episodesToKeep,
+// and files of older posts are removed. Files already downloaded are skipped, unless
+// they were deleted from the disk. The enclosures must come sorted from the newest to
+// the oldest post of each feed, as returned by GetEnclosuresToDownload.
+func planDownloads(enclosures []database.GetEnclosuresToDownloadRow, feedURL string,
+	episodesToKeep func(feedURL string) int) ([]database.GetEnclosuresToDownloadRow, []database.GetEnclosuresToDownloadRow) {
+	var toDownload, toRemove []database.GetEnclosuresToDownloadRow
+
+	// a post may come with several media files (different formats or bitrates of the
+	// same episode), which count as a single episode
+	episodes := make(map[string]int)
+	lastPost := make(map[string]uuid.UUID)
+	for _, enclosure := range enclosures {
+		if feedURL != "" && enclosure.FeedUrl != feedURL {
+			continue
+		}
+		if !isPlayable(enclosure.MediaType) {
+			continue
+		}
+
+		if lastPost[enclosure.FeedUrl] != enclosure.PostID {
+			lastPost[enclosure.FeedUrl] = enclosure.PostID
+			episodes[enclosure.FeedUrl]++
+		}
+		if episodes[enclosure.FeedUrl] > episodesToKeep(enclosure.FeedUrl) {
+			if enclosure.DownloadPath.Valid {
+				toRemove = append(toRemove, enclosure)
+			}
+			continue
+		}
+
+		if enclosure.DownloadPath.Valid {
+			if _, err := os.Stat(enclosure.DownloadPath.String); !errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+		toDownload = append(toDownload, enclosure)
+	}
+
+	return toDownload, toRemove
+}
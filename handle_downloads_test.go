@@ -0,0 +1,56 @@
+package main
+
+import (
+	"database/sql"
+	"gator/internal/database"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPlanDownloads(t *testing.T) {
+	downloaded := filepath.Join(t.TempDir(), "missing.mp3")
+	posts := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	enclosure := func(feedURL string, post uuid.UUID, name, mediaType string, path string) database.GetEnclosuresToDownloadRow {
+		return database.GetEnclosuresToDownloadRow{
+			EnclosureID:  uuid.New(),
+			EnclosureUrl: "https://cdn.example.com/" + name,
+			MediaType:    mediaType,
+			PostID:       post,
+			PostTitle:    name,
+			FeedUrl:      feedURL,
+			DownloadPath: sql.NullString{String: path, Valid: path != ""},
+		}
+	}
+	enclosures := []database.GetEnclosuresToDownloadRow{
+		// the latest episode comes in two bitrates, which count as one episode
+		enclosure("https://a.example.com/feed", posts[0], "3-low.mp3", "audio/mpeg", ""),
+		enclosure("https://a.example.com/feed", posts[0], "3-high.mp3", "audio/mpeg", ""),
+		enclosure("https://a.example.com/feed", posts[0], "3.pdf", "application/pdf", ""),
+		enclosure("https://a.example.com/feed", posts[1], "2.mp3", "audio/mpeg", downloaded),
+		enclosure("https://a.example.com/feed", posts[2], "1.mp3", "audio/mpeg", "/downloads/1.mp3"),
+		enclosure("https://b.example.com/feed", uuid.New(), "b.mp4", "video/mp4", ""),
+	}
+	keep := func(feedURL string) int { return 2 }
+
+	toDownload, toRemove := planDownloads(enclosures, "", keep)
+	assertNames(t, "to download", toDownload, "3-low.mp3", "3-high.mp3", "2.mp3", "b.mp4")
+	assertNames(t, "to remove", toRemove, "1.mp3")
+
+	toDownload, toRemove = planDownloads(enclosures, "https://b.example.com/feed", keep)
+	assertNames(t, "to download from a single feed", toDownload, "b.mp4")
+	assertNames(t, "to remove from a single feed", toRemove)
+}
+
+func assertNames(t *testing.T, what string, enclosures []database.GetEnclosuresToDownloadRow, want ...string) {
+	t.Helper()
+	var got []string
+	for _, enclosure := range enclosures {
+		got = append(got, enclosure.PostTitle)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("%v = %q, want %q", what, got, want)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"gator/internal/database"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// handlerFeedConfig allows the user who added a feed to change how it's fetched: the
+// User-Agent header, extra headers and Basic authentication credentials. Header values
+// can refer to a secret in the secrets file with the "secret:<name>" syntax, which is
+// mandatory for headers that carry credentials, and the password for Basic
+// authentication is always given as the name of such a secret, so credentials are
+// never stored in the database. Without options, the current settings are printed.
+//
+// It returns a non-nil error if the feed isn't stored in the database, the user didn't
+// add it, a secret can't be found, the settings couldn't be stored or the user made a
+// mistake when calling the command.
+func handlerFeedConfig(s *state, cmd command, userData database.User) error {
+	usage := fmt.Errorf("usage: %v <feed URL> [--user-agent <user agent>] [--header \"<name>: <value>\"]... "+
+		"[--remove-header <name>]... [--username <username> --password-secret <secret name>] [--no-auth] [--reset]", cmd.name)
+	if len(cmd.arguments) < 1 || strings.HasPrefix(cmd.arguments[0], "-") {
+		return usage
+	}
+
+	ctx := context.Background()
+	feedURL := cmd.arguments[0]
+	feed, err := s.db.GetFeedRequestSettings(ctx, feedURL)
+	if err != nil {
+		return fmt.Errorf("getting feed record from the database: %w", err)
+	}
+	if feed.UserID != userData.ID {
+		return fmt.Errorf("only the user who added the feed at %v can change how it's fetched", feedURL)
+	}
+
+	settings := feedRequestSettings{
+		UserAgent:      feed.UserAgent.String,
+		Headers:        feed.RequestHeaders,
+		Username:       feed.AuthUsername.String,
+		PasswordSecret: feed.AuthPasswordSecret.String,
+	}
+
+	options := cmd.arguments[1:]
+	for i := 0; i < len(options); i++ {
+		option := options[i]
+		switch option {
+		case "--no-auth":
+			settings.Username, settings.PasswordSecret = "", ""
+			continue
+		case "--reset":
+			settings = feedRequestSettings{}
+			continue
+		}
+		if i+1 == len(options) {
+			return usage
+		}
+		i++
+		value := options[i]
+
+		switch option {
+		case "--user-agent":
+			settings.UserAgent = strings.TrimSpace(value)
+		case "--header":
+			name, _, err := parseHeaderLine(value)
+			if err != nil {
+				return err
+			}
+			settings.Headers = append(removeHeader(settings.Headers, name), value)
+		case "--remove-header":
+			settings.Headers = removeHeader(settings.Headers, value)
+		case "--username":
+			settings.Username = value
+		case "--password-secret":
+			settings.PasswordSecret = value
+		default:
+			return usage
+		}
+	}
+
+	if len(options) > 0 {
+		if (settings.Username == "") != (settings.PasswordSecret == "") {
+			return fmt.Errorf("authenticating with Basic auth needs both a username and a password secret: %w", usage)
+		}
+		// resolving the headers makes sure the secrets they refer to exist
+		if _, err := settings.header(s.cfg); err != nil {
+			return fmt.Errorf("checking the new settings: %w", err)
+		}
+
+		// the column can't be NULL, which is how a nil slice is stored
+		headers := settings.Headers
+		if headers == nil {
+			headers = []string{}
+		}
+		if err := s.db.UpdateFeedRequestSettings(ctx, database.UpdateFeedRequestSettingsParams{
+			UserAgent:          nullString(settings.UserAgent),
+			RequestHeaders:     headers,
+			AuthUsername:       nullString(settings.Username),
+			AuthPasswordSecret: nullString(settings.PasswordSecret),
+			UpdatedAt:          time.Now().UTC(),
+			ID:                 feed.ID,
+		}); err != nil {
+			return fmt.Errorf("storing feed settings in the database: %w", err)
+		}
+	}
+
+	fmt.Printf("requests to %v are made with\n", feedURL)
+	fmt.Printf("\tuser agent: %v\n", cmp.Or(settings.UserAgent, "gator (default)"))
+	for _, line := range settings.Headers {
+		fmt.Printf("\theader: %v\n", line)
+	}
+	if settings.Username != "" {
+		fmt.Printf("\tbasic auth: %v (password from secret %q)\n", settings.Username, settings.PasswordSecret)
+	}
+
+	return nil
+}
+
+// removeHeader returns the header lines that don't set the header called name.
+func removeHeader(lines []string, name string) []string {
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	return slices.DeleteFunc(slices.Clone(lines), func(line string) bool {
+		lineName, _, _ := strings.Cut(line, ":")
+		return http.CanonicalHeaderKey(strings.TrimSpace(lineName)) == name
+	})
+}
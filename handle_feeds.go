@@ -2,75 +2,196 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"gator/internal/database"
 	"log"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// aggShutdownTimeout is how long handlerAgg waits for in-flight fetches to finish
+// after receiving a signal to stop.
+const aggShutdownTimeout = 30 * time.Second
+
 // handlerAgg starts a loop that fetches feeds indefinitely. It blocks the program
-// execution, and can only be terminated pressing CTRL-C. The users need to use the
+// execution until it receives SIGINT (CTRL-C) or SIGTERM. The users need to use the
 // program in a different instance (shell) to the one that is running `handlerAgg`.
 //
+// On SIGINT or SIGTERM, handlerAgg stops claiming feeds and waits for the feeds being
+// fetched to be stored before returning, which lets the program exit with status 0.
+// Work still in flight after aggShutdownTimeout is cancelled. A second signal kills
+// the program immediately.
+//
 // The function takes a human readable time interval expressed in "ms", "s", "m" or
 // "h" for miliseconds, seconds, minutes and hours, respectively. They can be mixed
 // with each other. For example, "1h10m20s" is a valid interval of 1 hour, 10 minutes
 // and 20 seconds.
 //
-// handlerAgg returns a non-nil error only when the received time interval couldn't
-// be parsed. In case there was a problem fetching a feed, the error will be logged
+// It optionally takes the number of feeds to fetch concurrently on every tick with
+// `--workers N`. By default, it fetches a single feed per tick.
+//
+// handlerAgg returns a non-nil error only when the received arguments couldn't be
+// parsed. In case there was a problem fetching a feed, the error will be logged
 // without killing the program so it keeps working on fetching the next feed in the
 // queue.
 func handlerAgg(s *state, cmd command) error {
-	if len(cmd.arguments) != 1 {
-		return fmt.Errorf("usage: %v <time between requests>", cmd.name)
+	usage := fmt.Errorf("usage: %v <time between requests> [--workers N]", cmd.name)
+
+	var interval string
+	workers := 1
+	for i := 0; i < len(cmd.arguments); i++ {
+		arg := cmd.arguments[i]
+		switch {
+		case arg == "--workers" && i+1 < len(cmd.arguments):
+			i++
+			arg = "--workers=" + cmd.arguments[i]
+			fallthrough
+		case strings.HasPrefix(arg, "--workers="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--workers="))
+			if err != nil || n < 1 {
+				return fmt.Errorf("the number of workers must be a positive integer: %w", usage)
+			}
+			workers = n
+		case interval == "" && !strings.HasPrefix(arg, "-"):
+			interval = arg
+		default:
+			return usage
+		}
+	}
+	if interval == "" {
+		return usage
 	}
 
-	timeBetweenRequests, err := time.ParseDuration(cmd.arguments[0])
+	timeBetweenRequests, err := time.ParseDuration(interval)
 	if err != nil {
 		return fmt.Errorf("parsing the time between requests parameter: %w", err)
 	}
-	fmt.Printf("Collecting feeds every %v starting right now\n", timeBetweenRequests)
+	fmt.Printf("Collecting %v feed(s) every %v starting right now\n", workers, timeBetweenRequests)
+
+	// `ctx` is cancelled as soon as we receive a signal, while `workCtx`, which is the
+	// one used to fetch and store feeds, is cancelled only after the grace period
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+	context.AfterFunc(ctx, func() {
+		// restore the default behavior so a second signal kills the program
+		stop()
+		log.Printf("shutting down: waiting up to %v for in-flight fetches", aggShutdownTimeout)
+		time.AfterFunc(aggShutdownTimeout, cancelWork)
+	})
 
 	ticker := time.NewTicker(timeBetweenRequests)
+	defer ticker.Stop()
 	// block execution by not using a goroutine and start fetching feeds immediately
 	// by using an empty for-condition (we should have used `for range ticker.C` or
 	// `for t := range ticker.C` if we want the ticker `t` timestamp to wait for the
 	// first tick to start fetching feeds)
 	t := time.Now()
 	for {
-		if err := scrapeFeeds(s); err != nil {
+		if err := scrapeFeeds(workCtx, s, workers); err != nil {
 			log.Printf("%v - found error while scraping feeds: %v", t.UTC(), err)
 		}
 		// we reassign the value of the `t` we declared and assigned before the for-block
-		t = <-ticker.C
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopped collecting feeds")
+			return nil
+		case t = <-ticker.C:
+		}
 	}
 
 }
 
 // handlerAddFeed allows the user to add a feed to the database and start following it.
 //
-// It takes a name for the new feed and its URL.
+// It takes a name for the new feed and its URL. The URL can also point to a website,
+// in which case handlerAddFeed looks for the feeds it publishes. If there's a single
+// one, it's added; if there are several, they're listed so the user can pick one.
+//
+// Before storing anything, the feed is fetched and parsed to make sure it works, and its
+// format, title and number of items are reported. Feeds that fail this check are only
+// added when the --force flag is given. The name can be omitted, in which case the
+// feed's own title is used.
 //
 // It returns a non-nil error if there was a problem adding the feed to the database
 // (for example, when the feed already exists), if it wasn't possible to make the user
 // follow it or the user made a mistake when calling the command.
 func handlerAddFeed(s *state, cmd command, userData database.User) error {
-	if len(cmd.arguments) != 2 {
-		return fmt.Errorf("usage: %v <feed name> <feed URL>", cmd.name)
+	usage := fmt.Errorf("usage: %v [--force] [feed name] <feed URL>", cmd.name)
+	force := false
+	var args []string
+	for _, arg := range cmd.arguments {
+		if arg == "--force" {
+			force = true
+			continue
+		}
+		args = append(args, arg)
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return usage
 	}
 
 	ctx := context.Background()
+	feedURL := args[len(args)-1]
+	feedName := ""
+	if len(args) == 2 {
+		feedName = args[0]
+	}
+	found, err := s.fetcher.discoverFeeds(ctx, feedURL)
+	if err != nil {
+		return fmt.Errorf("looking for feeds at %v: %w", feedURL, err)
+	}
+	if len(found.URLs) > 1 {
+		var list strings.Builder
+		for _, candidate := range found.URLs {
+			fmt.Fprintf(&list, "\n- %v", candidate)
+		}
+		return fmt.Errorf("%v publishes several feeds, add one of them instead:%v", feedURL, list.String())
+	}
+	if found.URLs[0] != feedURL {
+		fmt.Printf("found feed at %v\n", found.URLs[0])
+		feedURL = found.URLs[0]
+	}
+
+	// the feed is fetched to validate it unless discovering it already did
+	feed, cache, err := found.Feed, found.Cache, found.Err
+	if feed == nil && err == nil {
+		feed, cache, err = s.fetcher.fetchFeed(ctx, feedURL, nil, cacheHeaders{})
+	}
+	switch {
+	case err != nil && !force:
+		return fmt.Errorf("validating feed at %v: %w (use --force to add it anyway)", feedURL, err)
+	case err != nil:
+		fmt.Printf("couldn't validate feed at %v, adding it anyway: %v\n", feedURL, err)
+	default:
+		fmt.Printf("found %v feed %q with %d item(s)\n", feed.Format, feed.Channel.Title, len(feed.Channel.Item))
+		if feedName == "" {
+			feedName = strings.TrimSpace(feed.Channel.Title)
+		}
+		if cache.MovedTo != "" {
+			fmt.Printf("feed moved permanently to %v\n", cache.MovedTo)
+			feedURL = cache.MovedTo
+		}
+	}
+	if feedName == "" {
+		return fmt.Errorf("the feed at %v has no title, please give it a name: %w", feedURL, usage)
+	}
+
 	timestamp := time.Now().UTC()
 	feedParams := database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: timestamp,
 		UpdatedAt: timestamp,
-		Name:      cmd.arguments[0],
-		Url:       cmd.arguments[1],
+		Name:      feedName,
+		Url:       feedURL,
 		UserID:    userData.ID,
 	}
 
@@ -78,7 +199,7 @@ func handlerAddFeed(s *state, cmd command, userData database.User) error {
 		return fmt.Errorf("storing feed data to the database: %w", err)
 	}
 
-	if err := handlerFollow(s, command{name: cmd.name, arguments: []string{cmd.arguments[1]}}, userData); err != nil {
+	if err := handlerFollow(s, command{name: cmd.name, arguments: []string{feedURL}}, userData); err != nil {
 		return fmt.Errorf("following feed after adding it: %w", err)
 	}
 
@@ -86,7 +207,9 @@ func handlerAddFeed(s *state, cmd command, userData database.User) error {
 }
 
 // handlerListAllFeeds lists all feeds registered in the database. It prints the name
-// of the feed, the URL, and the username of the user that added it to the database.
+// of the feed, the URL, the username of the user that added it to the database, and
+// the health of the feed, reporting failing feeds along with their last error. Below
+// that, it prints the metadata published by the feed (title, description, etc.).
 //
 // This function doesn't take arguments.
 //
@@ -104,7 +227,22 @@ func handlerListAllFeeds(s *state, cmd command) error {
 	}
 
 	for _, feed := range feeds {
-		fmt.Printf("%q\t%v\t%v\n", feed.FeedName, feed.FeedUrl, feed.UserName)
+		fmt.Printf("%q\t%v\t%v\t%v\n", feed.FeedName, feed.FeedUrl, feed.UserName, feedHealth(feed))
+		for _, field := range []struct {
+			name  string
+			value sql.NullString
+		}{
+			{"title", feed.Title},
+			{"description", feed.Description},
+			{"site", feed.SiteUrl},
+			{"language", feed.Language},
+			{"image", feed.ImageUrl},
+			{"generator", feed.Generator},
+		} {
+			if field.value.Valid {
+				fmt.Printf("\t%v: %v\n", field.name, field.value.String)
+			}
+		}
 	}
 
 	return nil
@@ -217,17 +355,34 @@ func handlerBrowse(s *state, cmd command, userData database.User) error {
 		return fmt.Errorf("getting feed follows from the database: %w", err)
 	}
 
-	for _, feedFollow := range feedFollows {
-		posts, err := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{
-			FeedID: feedFollow.FeedID, Limit: limit,
-		})
-		if err != nil {
-			log.Printf("[NOT OK] getting posts from %q for %v: %v", feedFollow.FeedName, userData.Name, err)
-		}
+	posts, err := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+		UserID: userData.ID, PostsPerFeed: int64(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("getting posts from the database: %w", err)
+	}
+	// the posts are numbered from the latest one across all feeds, which is the index
+	// the read command takes
+	postsByFeed := make(map[uuid.UUID][]database.GetPostsForUserRow)
+	for _, post := range posts {
+		postsByFeed[post.FeedID] = append(postsByFeed[post.FeedID], post)
+	}
 
+	for _, feedFollow := range feedFollows {
 		fmt.Printf("---\n%v\n", feedFollow.FeedName)
-		for _, post := range posts {
-			fmt.Printf("- %v\n", post.Title)
+		for _, post := range postsByFeed[feedFollow.FeedID] {
+			fmt.Printf("- [%d] %v (%v)\n", post.PostIndex, post.Title, post.ID)
+			if summary := excerpt(post.DescriptionText, 100); summary != "" {
+				fmt.Printf("    %v\n", summary)
+			}
+
+			enclosures, err := s.db.GetEnclosuresForPost(ctx, post.ID)
+			if err != nil {
+				log.Printf("[NOT OK] getting enclosures of %q: %v", post.Title, err)
+			}
+			for _, enclosure := range enclosures {
+				fmt.Printf("    %v\n", formatEnclosure(enclosure))
+			}
 		}
 
 	}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+// handlerRead shows a single post in the terminal: its title, the feed it belongs to,
+// its publication date, author and URL, followed by its description rendered as
+// wrapped text, with links listed as footnotes. The post is given by its ID, as shown
+// by browse, or by its position among the posts of the feeds followed by the user,
+// 1 being the latest one. When the output is a terminal, the post is shown through
+// the pager set in $PAGER (less by default).
+//
+// It returns a non-nil error if the post can't be found among the posts of the feeds
+// followed by the user, there was a problem querying the database or the user made a
+// mistake when calling the command.
+func handlerRead(s *state, cmd command, userData database.User) error {
+	if len(cmd.arguments) != 1 {
+		return fmt.Errorf("usage: %v <post ID | index>", cmd.name)
+	}
+
+	ctx := context.Background()
+	var post database.GetPostForUserRow
+	var err error
+	if id, parseErr := uuid.Parse(cmd.arguments[0]); parseErr == nil {
+		post, err = s.db.GetPostForUser(ctx, database.GetPostForUserParams{UserID: userData.ID, ID: id})
+	} else {
+		index, parseErr := strconv.ParseInt(cmd.arguments[0], 10, 32)
+		if parseErr != nil || index < 1 {
+			return fmt.Errorf("expected the ID of a post or a positive index, got %q", cmd.arguments[0])
+		}
+		var row database.GetNthLatestPostForUserRow
+		row, err = s.db.GetNthLatestPostForUser(ctx, database.GetNthLatestPostForUserParams{
+			UserID: userData.ID, Offset: int32(index - 1),
+		})
+		post = database.GetPostForUserRow(row)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no post %v among the posts of the feeds followed by %v", cmd.arguments[0], userData.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("getting post from the database: %w", err)
+	}
+
+	return page(formatPost(post, terminalWidth()))
+}
+
+// formatPost renders a post as text wrapped to width columns.
+func formatPost(post database.GetPostForUserRow, width int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v\n\n", strings.Join(wrapLine(post.Title, width), "\n"))
+	fmt.Fprintf(&b, "Feed:      %v\n", post.FeedName)
+	fmt.Fprintf(&b, "Published: %v\n", post.PublishedAt.Local().Format(time.DateTime))
+	if post.Author != "" {
+		fmt.Fprintf(&b, "Author:    %v\n", post.Author)
+	}
+	fmt.Fprintf(&b, "URL:       %v\n", post.Url)
+	fmt.Fprintf(&b, "ID:        %v\n", post.ID)
+	// descriptions are stored sanitized, but rendering them again is how their
+	// paragraphs get wrapped without touching preformatted text
+	if text := wrappedText(sanitizeHTML(post.Description), width); text != "" {
+		fmt.Fprintf(&b, "\n%v\n", text)
+	}
+	return b.String()
+}
+
+// terminalWidth returns the number of columns of the terminal the output is written
+// to or, when it isn't one, the number set in $COLUMNS, defaulting to 80.
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
+
+// page writes text to the standard output, through the pager set in $PAGER (less by
+// default) when it's a terminal. The text is printed directly if the pager can't be
+// started.
+func page(text string) error {
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		_, err := fmt.Print(text)
+		return err
+	}
+
+	args := strings.Fields(cmp.Or(os.Getenv("PAGER"), "less"))
+	if len(args) == 0 {
+		_, err := fmt.Print(text)
+		return err
+	}
+	pager := exec.Command(args[0], args[1:]...)
+	pager.Stdin = strings.NewReader(text)
+	pager.Stdout, pager.Stderr = os.Stdout, os.Stderr
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		// quit right away when the post fits in the screen, and show the text as is
+		pager.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := pager.Start(); err != nil {
+		_, err := fmt.Print(text)
+		return err
+	}
+	if err := pager.Wait(); err != nil {
+		return fmt.Errorf("running pager %v: %w", args[0], err)
+	}
+	return nil
+}
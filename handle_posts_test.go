@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gator/internal/database"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBrowseIndexMatchesRead(t *testing.T) {
+	_, queries, user := testDatabase(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// posts of two feeds, published alternately
+	feed := 0
+	for feedID := range createTestFeeds(t, queries, user, 2) {
+		feed++
+		if _, err := queries.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: user.ID, FeedID: feedID,
+		}); err != nil {
+			t.Fatalf("following test feed: %v", err)
+		}
+		var items []RSSItem
+		for i := range 5 {
+			link := fmt.Sprintf("https://example.com/%v/%d", feedID, i)
+			items = append(items, RSSItem{
+				Title:   link,
+				Link:    link,
+				PubDate: now.Add(-time.Duration(2*i+feed) * time.Hour).Format(time.RFC3339),
+			})
+		}
+		if _, err := queries.UpsertPosts(ctx, postsBatch(feedID, now, items)); err != nil {
+			t.Fatalf("storing test posts: %v", err)
+		}
+	}
+
+	posts, err := queries.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: user.ID, PostsPerFeed: 3})
+	if err != nil {
+		t.Fatalf("GetPostsForUser() returned error: %v", err)
+	}
+	if len(posts) != 6 {
+		t.Fatalf("GetPostsForUser() returned %d posts, want 3 per feed", len(posts))
+	}
+	for _, post := range posts {
+		nth, err := queries.GetNthLatestPostForUser(ctx, database.GetNthLatestPostForUserParams{
+			UserID: user.ID, Offset: int32(post.PostIndex - 1),
+		})
+		if err != nil {
+			t.Fatalf("GetNthLatestPostForUser() returned error: %v", err)
+		}
+		if nth.ID != post.ID {
+			t.Errorf("browse lists %q as post %d, but read shows %q", post.Title, post.PostIndex, nth.Title)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"gator/internal/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter keeps gator from hammering a single host: it caps the number of
+// requests in flight to each host and spaces out the moment they start. Hosts
+// covered by a domain listed in the per-host settings share the limits of that
+// domain, so every subdomain of a blogging platform counts as the same host.
+type hostLimiter struct {
+	defaults config.HostSettings
+	perHost  map[string]config.HostSettings
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots tracks the requests made to a single host (or domain).
+type hostSlots struct {
+	settings config.HostSettings
+	inFlight chan struct{}
+	// next is the earliest time at which the next request can start
+	next time.Time
+}
+
+func newHostLimiter(defaults config.HostSettings, perHost map[string]config.HostSettings) *hostLimiter {
+	return &hostLimiter{
+		defaults: defaults,
+		perHost:  perHost,
+		hosts:    make(map[string]*hostSlots),
+	}
+}
+
+// acquire blocks until a request to host is allowed to start, or ctx is cancelled.
+// The caller must call the returned function once the request is done.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	slots := l.slots(host)
+
+	select {
+	case slots.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-slots.inFlight }
+
+	l.mu.Lock()
+	now := time.Now()
+	start := slots.next
+	if start.Before(now) {
+		start = now
+	}
+	slots.next = start.Add(slots.settings.Interval)
+	l.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// slots returns the tracker of the requests made to host, creating it if needed.
+func (l *hostLimiter) slots(host string) *hostSlots {
+	key, settings := l.settingsFor(strings.ToLower(host))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots, ok := l.hosts[key]
+	if !ok {
+		slots = &hostSlots{
+			settings: settings,
+			inFlight: make(chan struct{}, max(settings.Concurrency, 1)),
+		}
+		l.hosts[key] = slots
+	}
+	return slots
+}
+
+// settingsFor returns the limits that apply to host, along with the key under which
+// its requests are tracked: the host itself, or the closest domain with its own
+// settings.
+func (l *hostLimiter) settingsFor(host string) (string, config.HostSettings) {
+	for domain := host; domain != ""; {
+		if settings, ok := l.perHost[domain]; ok {
+			return domain, settings
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return host, l.defaults
+}
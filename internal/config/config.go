@@ -1,17 +1,127 @@
 package config
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 const configFileName = ".gatorconfig.json"
 
+// defaultSecretsFileName is the name of the file in $HOME that holds the secrets
+// feeds refer to, unless another file is configured
+const defaultSecretsFileName = ".gator-secrets.json"
+
+// default bounds of the interval between fetches of the same feed
+const (
+	defaultMinRefreshInterval time.Duration = 0
+	defaultMaxRefreshInterval               = 24 * time.Hour
+)
+
+// default settings of the `download` command
+const (
+	defaultDownloadDir  = "gator-downloads"
+	defaultDownloadKeep = 5
+)
+
+// default settings of the HTTP client used to fetch feeds
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultFetchTimeout   = 30 * time.Second
+	defaultMaxBodySize    = 20 << 20
+	defaultMaxRedirects   = 10
+)
+
+// default limits of the requests made to a single host
+const (
+	defaultHostInterval    = time.Second
+	defaultHostConcurrency = 2
+)
+
 type Config struct {
 	DbUrl           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	// MinRefreshInterval and MaxRefreshInterval bound the interval between fetches of
+	// the same feed, and are expressed as durations like "15m" or "12h"
+	MinRefreshInterval string `json:"min_refresh_interval,omitempty"`
+	MaxRefreshInterval string `json:"max_refresh_interval,omitempty"`
+	// DownloadDir is where the `download` command stores media files, and defaults
+	// to a directory in $HOME
+	DownloadDir string `json:"download_dir,omitempty"`
+	// DownloadKeep is the number of episodes to keep per feed, which can be
+	// overridden for specific feeds (identified by their URLs) in DownloadKeepPerFeed
+	DownloadKeep        int            `json:"download_keep,omitempty"`
+	DownloadKeepPerFeed map[string]int `json:"download_keep_per_feed,omitempty"`
+	// ConnectTimeout bounds the time it takes to connect to a server, while
+	// FetchTimeout bounds the whole request, including reading the response body. Both
+	// are expressed as durations like "10s" or "1m"
+	ConnectTimeout string `json:"connect_timeout,omitempty"`
+	FetchTimeout   string `json:"fetch_timeout,omitempty"`
+	// MaxBodySize is the largest response body, in bytes, accepted when fetching feeds
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+	// MaxRedirects is the number of redirects followed before giving up on a request.
+	// It's a pointer so that 0, which disables redirects, can be told apart from a
+	// missing value
+	MaxRedirects *int `json:"max_redirects,omitempty"`
+	// HostInterval is the minimum time between the start of two requests to the same
+	// host, expressed as a duration like "500ms" or "2s", and HostConcurrency is the
+	// maximum number of requests to the same host in flight at once. Both can be
+	// overridden for specific hosts (or domains, covering their subdomains too) in
+	// HostLimits
+	HostInterval    string               `json:"host_interval,omitempty"`
+	HostConcurrency int                  `json:"host_concurrency,omitempty"`
+	HostLimits      map[string]HostLimit `json:"host_limits,omitempty"`
+	// SecretsFile is a JSON file mapping names to secrets, like passwords or tokens,
+	// which feeds refer to by name so they're never stored in the database
+	SecretsFile string `json:"secrets_file,omitempty"`
+	// Proxy is the URL of the HTTP, HTTPS or SOCKS5 proxy requests go through, which
+	// defaults to the one set in the environment (HTTP_PROXY, HTTPS_PROXY), and
+	// NoProxy lists the hosts (or domains, covering their subdomains too) and feed
+	// URLs that are fetched without it. Feed URLs must match exactly, so they don't
+	// follow feeds that move
+	Proxy   string   `json:"proxy,omitempty"`
+	NoProxy []string `json:"no_proxy,omitempty"`
+	// CAFile is a PEM bundle with certificate authorities to trust on top of the
+	// system ones, and ClientCertFile and ClientKeyFile are the PEM files with the
+	// certificate and key gator presents to servers asking for one
+	CAFile         string `json:"ca_file,omitempty"`
+	ClientCertFile string `json:"client_cert_file,omitempty"`
+	ClientKeyFile  string `json:"client_key_file,omitempty"`
+}
+
+// HostLimit overrides the limits of the requests made to a host.
+type HostLimit struct {
+	Interval    string `json:"interval,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+}
+
+// FetchSettings holds the settings of the HTTP client used to fetch feeds.
+type FetchSettings struct {
+	ConnectTimeout time.Duration
+	Timeout        time.Duration
+	MaxBodySize    int64
+	MaxRedirects   int
+	// Hosts holds the limits of the requests made to a single host, while PerHost
+	// holds the limits of specific hosts or domains
+	Hosts   HostSettings
+	PerHost map[string]HostSettings
+	// Proxy is nil when the proxy set in the environment, if any, must be used
+	Proxy          *url.URL
+	NoProxy        []string
+	CAFile         string
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// HostSettings holds the limits of the requests made to a single host.
+type HostSettings struct {
+	Interval    time.Duration
+	Concurrency int
 }
 
 func getConfigFilePath() (string, error) {
@@ -79,3 +189,184 @@ func (c *Config) FormatPrettyJSON() ([]byte, error) {
 	}
 	return jsonData, nil
 }
+
+// RefreshIntervals returns the configured bounds of the interval between fetches of
+// the same feed, falling back to the default bounds for the missing values.
+func (c *Config) RefreshIntervals() (time.Duration, time.Duration, error) {
+	minInterval, maxInterval := defaultMinRefreshInterval, defaultMaxRefreshInterval
+
+	if c.MinRefreshInterval != "" {
+		d, err := time.ParseDuration(c.MinRefreshInterval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("couldn't parse min_refresh_interval: %w", err)
+		}
+		minInterval = d
+	}
+
+	if c.MaxRefreshInterval != "" {
+		d, err := time.ParseDuration(c.MaxRefreshInterval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("couldn't parse max_refresh_interval: %w", err)
+		}
+		maxInterval = d
+	}
+
+	if minInterval > maxInterval {
+		return 0, 0, fmt.Errorf("min_refresh_interval (%v) is greater than max_refresh_interval (%v)", minInterval, maxInterval)
+	}
+
+	return minInterval, maxInterval, nil
+}
+
+// DownloadDirectory returns the directory where media files are downloaded. A leading
+// "~/" in the configured directory is replaced with the $HOME directory.
+func (c *Config) DownloadDirectory() (string, error) {
+	return expandHome(cmp.Or(c.DownloadDir, "~/"+defaultDownloadDir))
+}
+
+// EpisodesToKeep returns the number of episodes of the feed located at feedURL to
+// keep downloaded.
+func (c *Config) EpisodesToKeep(feedURL string) int {
+	if n, ok := c.DownloadKeepPerFeed[feedURL]; ok && n > 0 {
+		return n
+	}
+	if c.DownloadKeep > 0 {
+		return c.DownloadKeep
+	}
+	return defaultDownloadKeep
+}
+
+// FetchSettings returns the configured settings of the HTTP client used to fetch
+// feeds, falling back to the defaults for the missing values.
+func (c *Config) FetchSettings() (FetchSettings, error) {
+	settings := FetchSettings{
+		ConnectTimeout: defaultConnectTimeout,
+		Timeout:        defaultFetchTimeout,
+		MaxBodySize:    defaultMaxBodySize,
+		MaxRedirects:   defaultMaxRedirects,
+		Hosts: HostSettings{
+			Interval:    defaultHostInterval,
+			Concurrency: defaultHostConcurrency,
+		},
+	}
+
+	if c.ConnectTimeout != "" {
+		d, err := time.ParseDuration(c.ConnectTimeout)
+		if err != nil {
+			return FetchSettings{}, fmt.Errorf("couldn't parse connect_timeout: %w", err)
+		}
+		settings.ConnectTimeout = d
+	}
+
+	if c.FetchTimeout != "" {
+		d, err := time.ParseDuration(c.FetchTimeout)
+		if err != nil {
+			return FetchSettings{}, fmt.Errorf("couldn't parse fetch_timeout: %w", err)
+		}
+		settings.Timeout = d
+	}
+
+	if c.MaxBodySize > 0 {
+		settings.MaxBodySize = c.MaxBodySize
+	}
+	if c.MaxRedirects != nil {
+		if *c.MaxRedirects < 0 {
+			return FetchSettings{}, fmt.Errorf("max_redirects can't be negative, got %v", *c.MaxRedirects)
+		}
+		settings.MaxRedirects = *c.MaxRedirects
+	}
+
+	if c.HostInterval != "" {
+		d, err := time.ParseDuration(c.HostInterval)
+		if err != nil {
+			return FetchSettings{}, fmt.Errorf("couldn't parse host_interval: %w", err)
+		}
+		settings.Hosts.Interval = d
+	}
+	if c.HostConcurrency > 0 {
+		settings.Hosts.Concurrency = c.HostConcurrency
+	}
+
+	settings.PerHost = make(map[string]HostSettings, len(c.HostLimits))
+	for host, limit := range c.HostLimits {
+		hostSettings := settings.Hosts
+		if limit.Interval != "" {
+			d, err := time.ParseDuration(limit.Interval)
+			if err != nil {
+				return FetchSettings{}, fmt.Errorf("couldn't parse interval of host_limits[%q]: %w", host, err)
+			}
+			hostSettings.Interval = d
+		}
+		if limit.Concurrency > 0 {
+			hostSettings.Concurrency = limit.Concurrency
+		}
+		settings.PerHost[strings.ToLower(host)] = hostSettings
+	}
+
+	if c.Proxy != "" {
+		proxy, err := url.Parse(c.Proxy)
+		if err != nil {
+			return FetchSettings{}, fmt.Errorf("couldn't parse proxy: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return FetchSettings{}, fmt.Errorf("unsupported proxy scheme %q, expected http, https, socks5 or socks5h", proxy.Scheme)
+		}
+		settings.Proxy = proxy
+	}
+	settings.NoProxy = c.NoProxy
+
+	if c.ClientKeyFile != "" && c.ClientCertFile == "" {
+		return FetchSettings{}, fmt.Errorf("client_key_file is set but client_cert_file isn't")
+	}
+	var err error
+	if settings.CAFile, err = expandHome(c.CAFile); err != nil {
+		return FetchSettings{}, err
+	}
+	if settings.ClientCertFile, err = expandHome(c.ClientCertFile); err != nil {
+		return FetchSettings{}, err
+	}
+	if settings.ClientKeyFile, err = expandHome(c.ClientKeyFile); err != nil {
+		return FetchSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// Secret returns the secret stored under name in the secrets file. A leading "~/" in
+// the configured path of the file is replaced with the $HOME directory.
+func (c *Config) Secret(name string) (string, error) {
+	path, err := expandHome(cmp.Or(c.SecretsFile, "~/"+defaultSecretsFileName))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't load secrets file: %w", err)
+	}
+
+	var secrets map[string]string
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return "", fmt.Errorf("couldn't unmarshal the contents of '%v': %w", path, err)
+	}
+
+	secret, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("couldn't find secret %q in '%v'", name, path)
+	}
+	return secret, nil
+}
+
+// expandHome replaces a leading "~/" in path with the $HOME directory.
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("couldn't get $HOME directory: %w", err)
+	}
+	return filepath.Join(home, path[2:]), nil
+}
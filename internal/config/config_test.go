@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHomeExpansion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "secrets.json"), []byte(`{"token": "hunter2"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		wantDir     string
+		wantCAFile  string
+		wantSecrets bool
+	}{
+		{
+			name:    "defaults",
+			cfg:     Config{},
+			wantDir: filepath.Join(home, defaultDownloadDir),
+		},
+		{
+			name:        "paths relative to home",
+			cfg:         Config{DownloadDir: "~/podcasts", SecretsFile: "~/secrets.json", CAFile: "~/ca.pem"},
+			wantDir:     filepath.Join(home, "podcasts"),
+			wantCAFile:  filepath.Join(home, "ca.pem"),
+			wantSecrets: true,
+		},
+		{
+			name:        "absolute paths",
+			cfg:         Config{DownloadDir: "/srv/podcasts", SecretsFile: filepath.Join(home, "secrets.json"), CAFile: "/etc/ca.pem"},
+			wantDir:     "/srv/podcasts",
+			wantCAFile:  "/etc/ca.pem",
+			wantSecrets: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := tt.cfg.DownloadDirectory()
+			if err != nil || dir != tt.wantDir {
+				t.Errorf("DownloadDirectory() = %q, %v, want %q", dir, err, tt.wantDir)
+			}
+
+			settings, err := tt.cfg.FetchSettings()
+			if err != nil || settings.CAFile != tt.wantCAFile {
+				t.Errorf("FetchSettings().CAFile = %q, %v, want %q", settings.CAFile, err, tt.wantCAFile)
+			}
+
+			secret, err := tt.cfg.Secret("token")
+			if tt.wantSecrets && (err != nil || secret != "hunter2") {
+				t.Errorf("Secret() = %q, %v, want %q", secret, err, "hunter2")
+			}
+			if !tt.wantSecrets && err == nil {
+				t.Errorf("Secret() found a secret in a missing secrets file")
+			}
+		})
+	}
+}
+
+func TestMaxRedirects(t *testing.T) {
+	redirects := func(n int) *int { return &n }
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    int
+		wantErr bool
+	}{
+		{name: "missing", cfg: Config{}, want: defaultMaxRedirects},
+		{name: "disabled", cfg: Config{MaxRedirects: redirects(0)}, want: 0},
+		{name: "set", cfg: Config{MaxRedirects: redirects(3)}, want: 3},
+		{name: "negative", cfg: Config{MaxRedirects: redirects(-1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := tt.cfg.FetchSettings()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("FetchSettings() accepted max_redirects %v", *tt.cfg.MaxRedirects)
+				}
+				return
+			}
+			if err != nil || settings.MaxRedirects != tt.want {
+				t.Errorf("FetchSettings().MaxRedirects = %v, %v, want %v", settings.MaxRedirects, err, tt.want)
+			}
+		})
+	}
+}
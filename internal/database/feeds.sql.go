@@ -11,8 +11,119 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = $1,
+    updated_at = $1,
+    next_fetch_at = $3
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+        AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, url, etag, last_modified, consecutive_failures,
+    user_agent, request_headers, auth_username, auth_password_secret,
+    ttl_seconds, update_interval_seconds, skip_hours, skip_days
+`
+
+type ClaimFeedsToFetchParams struct {
+	LastFetchedAt sql.NullTime
+	Limit         int32
+	NextFetchAt   sql.NullTime
+}
+
+type ClaimFeedsToFetchRow struct {
+	ID                    uuid.UUID
+	Url                   string
+	Etag                  sql.NullString
+	LastModified          sql.NullString
+	ConsecutiveFailures   int32
+	UserAgent             sql.NullString
+	RequestHeaders        []string
+	AuthUsername          sql.NullString
+	AuthPasswordSecret    sql.NullString
+	TtlSeconds            int32
+	UpdateIntervalSeconds int32
+	SkipHours             []int32
+	SkipDays              []int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]ClaimFeedsToFetchRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.LastFetchedAt, arg.Limit, arg.NextFetchAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimFeedsToFetchRow
+	for rows.Next() {
+		var i ClaimFeedsToFetchRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Etag,
+			&i.LastModified,
+			&i.ConsecutiveFailures,
+			&i.UserAgent,
+			pq.Array(&i.RequestHeaders),
+			&i.AuthUsername,
+			&i.AuthPasswordSecret,
+			&i.TtlSeconds,
+			&i.UpdateIntervalSeconds,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const copyFeedRequestSettings = `-- name: CopyFeedRequestSettings :exec
+UPDATE feeds
+SET user_agent = COALESCE(feeds.user_agent, source.user_agent),
+    request_headers = CASE
+        WHEN cardinality(feeds.request_headers) = 0 THEN source.request_headers
+        ELSE feeds.request_headers
+    END,
+    auth_username = CASE
+        WHEN feeds.auth_username IS NULL AND feeds.auth_password_secret IS NULL THEN source.auth_username
+        ELSE feeds.auth_username
+    END,
+    auth_password_secret = CASE
+        WHEN feeds.auth_username IS NULL AND feeds.auth_password_secret IS NULL THEN source.auth_password_secret
+        ELSE feeds.auth_password_secret
+    END,
+    updated_at = $1
+FROM feeds AS source
+WHERE feeds.id = $2
+    AND source.id = $3
+`
+
+type CopyFeedRequestSettingsParams struct {
+	UpdatedAt  time.Time
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) CopyFeedRequestSettings(ctx context.Context, arg CopyFeedRequestSettingsParams) error {
+	_, err := q.db.ExecContext(ctx, copyFeedRequestSettings, arg.UpdatedAt, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -23,7 +134,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, last_succeeded_at, next_fetch_at, title, description, site_url, language, image_url, generator, disabled_at, disabled_reason, user_agent, request_headers, auth_username, auth_password_secret
 `
 
 type CreateFeedParams struct {
@@ -53,6 +164,28 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.NextFetchAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.UserAgent,
+		pq.Array(&i.RequestHeaders),
+		&i.AuthUsername,
+		&i.AuthPasswordSecret,
+		&i.TtlSeconds,
+		&i.UpdateIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
@@ -108,47 +241,38 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
-const createPost = `-- name: CreatePost :exec
-INSERT INTO posts (
-    id,
-    created_at,
-    updated_at,
-    title,
-    url,
-    description,
-    published_at,
-    feed_id
-)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
-	_, err := q.db.ExecContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = $1,
+    disabled_reason = $2,
+    updated_at = $1
+WHERE id = $3
+`
+
+type DisableFeedParams struct {
+	DisabledAt     sql.NullTime
+	DisabledReason sql.NullString
+	ID             uuid.UUID
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, arg.DisabledAt, arg.DisabledReason, arg.ID)
 	return err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feeds.id AS feed_id, feeds.name AS feed_name, users.name AS user_name
+SELECT feeds.id AS feed_id, feeds.name AS feed_name, users.name AS user_name,
+    feeds.title, feeds.description, feeds.site_url, feeds.language, feeds.image_url, feeds.generator
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
@@ -156,9 +280,15 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	FeedID   uuid.UUID
-	FeedName string
-	UserName string
+	FeedID      uuid.UUID
+	FeedName    string
+	UserName    string
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -170,7 +300,17 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.FeedID, &i.FeedName, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.FeedID,
+			&i.FeedName,
+			&i.UserName,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -197,16 +337,60 @@ func (q *Queries) GetFeedIdByURL(ctx context.Context, url string) (uuid.UUID, er
 	return id, err
 }
 
+const getFeedRequestSettings = `-- name: GetFeedRequestSettings :one
+SELECT id, user_id, user_agent, request_headers, auth_username, auth_password_secret
+FROM feeds
+WHERE url = $1
+`
+
+type GetFeedRequestSettingsRow struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	UserAgent          sql.NullString
+	RequestHeaders     []string
+	AuthUsername       sql.NullString
+	AuthPasswordSecret sql.NullString
+}
+
+func (q *Queries) GetFeedRequestSettings(ctx context.Context, url string) (GetFeedRequestSettingsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedRequestSettings, url)
+	var i GetFeedRequestSettingsRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.UserAgent,
+		pq.Array(&i.RequestHeaders),
+		&i.AuthUsername,
+		&i.AuthPasswordSecret,
+	)
+	return i, err
+}
+
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name,
+    feeds.last_fetched_at, feeds.last_succeeded_at, feeds.consecutive_failures, feeds.last_error,
+    feeds.title, feeds.description, feeds.site_url, feeds.language, feeds.image_url, feeds.generator,
+    feeds.disabled_at, feeds.disabled_reason
 FROM feeds
 INNER JOIN users ON feeds.user_id = users.id
 `
 
 type GetFeedsRow struct {
-	FeedName string
-	FeedUrl  string
-	UserName string
+	FeedName            string
+	FeedUrl             string
+	UserName            string
+	LastFetchedAt       sql.NullTime
+	LastSucceededAt     sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	Title               sql.NullString
+	Description         sql.NullString
+	SiteUrl             sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	DisabledAt          sql.NullTime
+	DisabledReason      sql.NullString
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -218,7 +402,23 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.FeedName, &i.FeedUrl, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.UserName,
+			&i.LastFetchedAt,
+			&i.LastSucceededAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.DisabledAt,
+			&i.DisabledReason,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -232,47 +432,158 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, url
-FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const getNthLatestPostForUser = `-- name: GetNthLatestPostForUser :one
+SELECT posts.id, posts.title, posts.url, posts.published_at, posts.author, posts.description,
+    feeds.name AS feed_name
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC, posts.id
+LIMIT 1 OFFSET $2
 `
 
-type GetNextFeedToFetchRow struct {
-	ID  uuid.UUID
-	Url string
+type GetNthLatestPostForUserParams struct {
+	UserID uuid.UUID
+	Offset int32
 }
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i GetNextFeedToFetchRow
-	err := row.Scan(&i.ID, &i.Url)
+type GetNthLatestPostForUserRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt time.Time
+	Author      string
+	Description string
+	FeedName    string
+}
+
+func (q *Queries) GetNthLatestPostForUser(ctx context.Context, arg GetNthLatestPostForUserParams) (GetNthLatestPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getNthLatestPostForUser, arg.UserID, arg.Offset)
+	var i GetNthLatestPostForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Url,
+		&i.PublishedAt,
+		&i.Author,
+		&i.Description,
+		&i.FeedName,
+	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT title, published_at, url, description
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.title, posts.url, posts.published_at, posts.author, posts.description,
+    feeds.name AS feed_name
 FROM posts
-WHERE feed_id = $1
-ORDER BY published_at DESC
-LIMIT $2
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1 AND posts.id = $2
 `
 
-type GetPostsForUserParams struct {
-	FeedID uuid.UUID
-	Limit  int32
+type GetPostForUserParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
 }
 
-type GetPostsForUserRow struct {
+type GetPostForUserRow struct {
+	ID          uuid.UUID
 	Title       string
-	PublishedAt time.Time
 	Url         string
+	PublishedAt time.Time
+	Author      string
 	Description string
+	FeedName    string
+}
+
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (GetPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.UserID, arg.ID)
+	var i GetPostForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Url,
+		&i.PublishedAt,
+		&i.Author,
+		&i.Description,
+		&i.FeedName,
+	)
+	return i, err
+}
+
+const getPostIDsByGUID = `-- name: GetPostIDsByGUID :many
+SELECT id, guid
+FROM posts
+WHERE feed_id = $1 AND guid = ANY($2::text[])
+`
+
+type GetPostIDsByGUIDParams struct {
+	FeedID uuid.UUID
+	Guids  []string
+}
+
+type GetPostIDsByGUIDRow struct {
+	ID   uuid.UUID
+	Guid string
+}
+
+func (q *Queries) GetPostIDsByGUID(ctx context.Context, arg GetPostIDsByGUIDParams) ([]GetPostIDsByGUIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostIDsByGUID, arg.FeedID, pq.Array(arg.Guids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostIDsByGUIDRow
+	for rows.Next() {
+		var i GetPostIDsByGUIDRow
+		if err := rows.Scan(&i.ID, &i.Guid); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+SELECT id, title, published_at, url, description, description_text, feed_id, post_index
+FROM (
+    SELECT posts.id, posts.title, posts.published_at, posts.url, posts.description, posts.description_text,
+        posts.feed_id,
+        row_number() OVER (ORDER BY posts.published_at DESC, posts.id) AS post_index,
+        row_number() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC, posts.id) AS feed_rank
+    FROM posts
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    WHERE feed_follows.user_id = $1
+) AS ranked
+WHERE feed_rank <= $2::bigint
+ORDER BY post_index
+`
+
+type GetPostsForUserParams struct {
+	UserID       uuid.UUID
+	PostsPerFeed int64
+}
+
+type GetPostsForUserRow struct {
+	ID              uuid.UUID
+	Title           string
+	PublishedAt     time.Time
+	Url             string
+	Description     string
+	DescriptionText string
+	FeedID          uuid.UUID
+	PostIndex       int64
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.FeedID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.PostsPerFeed)
 	if err != nil {
 		return nil, err
 	}
@@ -281,10 +592,14 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	for rows.Next() {
 		var i GetPostsForUserRow
 		if err := rows.Scan(
+			&i.ID,
 			&i.Title,
 			&i.PublishedAt,
 			&i.Url,
 			&i.Description,
+			&i.DescriptionText,
+			&i.FeedID,
+			&i.PostIndex,
 		); err != nil {
 			return nil, err
 		}
@@ -299,20 +614,113 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	return items, nil
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1
+WHERE feed_id = $2
+    AND user_id NOT IN (
+        SELECT user_id
+        FROM feed_follows
+        WHERE feed_id = $1
+    )
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+    AND guid NOT IN (
+        SELECT guid
+        FROM posts
+        WHERE feed_id = $1
+    )
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
 UPDATE feeds
-SET last_fetched_at = $1,
-    updated_at = $1
-WHERE id = $2
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = $1,
+    next_fetch_at = $2
+WHERE id = $3
 `
 
-type MarkFeedFetchedParams struct {
-	LastFetchedAt sql.NullTime
-	ID            uuid.UUID
+type RecordFeedFailureParams struct {
+	LastError   sql.NullString
+	NextFetchAt sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure, arg.LastError, arg.NextFetchAt, arg.ID)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_succeeded_at = $1,
+    next_fetch_at = $2
+WHERE id = $3
+`
+
+type RecordFeedSuccessParams struct {
+	LastSucceededAt sql.NullTime
+	NextFetchAt     sql.NullTime
+	ID              uuid.UUID
 }
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.ID)
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.LastSucceededAt, arg.NextFetchAt, arg.ID)
+	return err
+}
+
+const rekeyLegacyPosts = `-- name: RekeyLegacyPosts :exec
+UPDATE posts
+SET guid = item.guid
+FROM unnest(
+    $1::text[],
+    $2::text[]
+) AS item(url, guid)
+WHERE posts.feed_id = $3::uuid
+    AND posts.url = item.url
+    AND posts.guid = posts.url
+    AND item.guid <> item.url
+    AND NOT EXISTS (
+        SELECT 1
+        FROM posts AS other
+        WHERE other.feed_id = $3::uuid AND other.guid = item.guid
+    )
+`
+
+type RekeyLegacyPostsParams struct {
+	Urls   []string
+	Guids  []string
+	FeedID uuid.UUID
+}
+
+func (q *Queries) RekeyLegacyPosts(ctx context.Context, arg RekeyLegacyPostsParams) error {
+	_, err := q.db.ExecContext(ctx, rekeyLegacyPosts, pq.Array(arg.Urls), pq.Array(arg.Guids), arg.FeedID)
 	return err
 }
 
@@ -330,3 +738,236 @@ func (q *Queries) UnfollowFeed(ctx context.Context, arg UnfollowFeedParams) erro
 	_, err := q.db.ExecContext(ctx, unfollowFeed, arg.UserID, arg.FeedID)
 	return err
 }
+
+const updateFeedCacheValidators = `-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $1,
+    last_modified = $2
+WHERE id = $3
+`
+
+type UpdateFeedCacheValidatorsParams struct {
+	Etag         sql.NullString
+	LastModified sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) UpdateFeedCacheValidators(ctx context.Context, arg UpdateFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheValidators, arg.Etag, arg.LastModified, arg.ID)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $1,
+    description = $2,
+    site_url = $3,
+    language = $4,
+    image_url = $5,
+    generator = $6
+WHERE id = $7
+`
+
+type UpdateFeedMetadataParams struct {
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedRefreshHints = `-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET ttl_seconds = $1,
+    update_interval_seconds = $2,
+    skip_hours = $3,
+    skip_days = $4
+WHERE id = $5
+`
+
+type UpdateFeedRefreshHintsParams struct {
+	TtlSeconds            int32
+	UpdateIntervalSeconds int32
+	SkipHours             []int32
+	SkipDays              []int32
+	ID                    uuid.UUID
+}
+
+func (q *Queries) UpdateFeedRefreshHints(ctx context.Context, arg UpdateFeedRefreshHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedRefreshHints,
+		arg.TtlSeconds,
+		arg.UpdateIntervalSeconds,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedRequestSettings = `-- name: UpdateFeedRequestSettings :exec
+UPDATE feeds
+SET user_agent = $1,
+    request_headers = $2,
+    auth_username = $3,
+    auth_password_secret = $4,
+    updated_at = $5
+WHERE id = $6
+`
+
+type UpdateFeedRequestSettingsParams struct {
+	UserAgent          sql.NullString
+	RequestHeaders     []string
+	AuthUsername       sql.NullString
+	AuthPasswordSecret sql.NullString
+	UpdatedAt          time.Time
+	ID                 uuid.UUID
+}
+
+func (q *Queries) UpdateFeedRequestSettings(ctx context.Context, arg UpdateFeedRequestSettingsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedRequestSettings,
+		arg.UserAgent,
+		pq.Array(arg.RequestHeaders),
+		arg.AuthUsername,
+		arg.AuthPasswordSecret,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1,
+    updated_at = $2
+WHERE id = $3
+`
+
+type UpdateFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const upsertPosts = `-- name: UpsertPosts :many
+INSERT INTO posts (
+    id,
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    description_text,
+    published_at,
+    feed_id,
+    guid,
+    author
+)
+SELECT
+    post.id,
+    $1::timestamp,
+    $1::timestamp,
+    post.title,
+    post.url,
+    post.description,
+    post.description_text,
+    post.published_at,
+    $2::uuid,
+    post.guid,
+    post.author
+FROM unnest(
+    $3::uuid[],
+    $4::text[],
+    $5::text[],
+    $6::text[],
+    $7::text[],
+    $8::timestamp[],
+    $9::text[],
+    $10::text[]
+) AS post(id, title, url, description, description_text, published_at, guid, author)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    description_text = EXCLUDED.description_text,
+    author = EXCLUDED.author,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.title <> EXCLUDED.title
+    OR posts.url <> EXCLUDED.url
+    OR posts.description <> EXCLUDED.description
+    OR posts.description_text <> EXCLUDED.description_text
+    OR posts.author <> EXCLUDED.author
+RETURNING id, guid, (xmax = 0) AS inserted
+`
+
+type UpsertPostsParams struct {
+	FetchedAt        time.Time
+	FeedID           uuid.UUID
+	Ids              []uuid.UUID
+	Titles           []string
+	Urls             []string
+	Descriptions     []string
+	DescriptionTexts []string
+	PublishedAts     []time.Time
+	Guids            []string
+	Authors          []string
+}
+
+type UpsertPostsRow struct {
+	ID       uuid.UUID
+	Guid     string
+	Inserted bool
+}
+
+func (q *Queries) UpsertPosts(ctx context.Context, arg UpsertPostsParams) ([]UpsertPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, upsertPosts,
+		arg.FetchedAt,
+		arg.FeedID,
+		pq.Array(arg.Ids),
+		pq.Array(arg.Titles),
+		pq.Array(arg.Urls),
+		pq.Array(arg.Descriptions),
+		pq.Array(arg.DescriptionTexts),
+		pq.Array(arg.PublishedAts),
+		pq.Array(arg.Guids),
+		pq.Array(arg.Authors),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UpsertPostsRow
+	for rows.Next() {
+		var i UpsertPostsRow
+		if err := rows.Scan(&i.ID, &i.Guid, &i.Inserted); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -11,14 +11,58 @@ import (
 	"github.com/google/uuid"
 )
 
+type Download struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	EnclosureID uuid.UUID
+	Path        string
+	Size        int64
+	Sha256      string
+}
+
+type Enclosure struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Url       string
+	MediaType string
+	Size      sql.NullInt64
+	Duration  sql.NullInt32
+	Episode   sql.NullInt32
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Name                  string
+	Url                   string
+	UserID                uuid.UUID
+	LastFetchedAt         sql.NullTime
+	Etag                  sql.NullString
+	LastModified          sql.NullString
+	ConsecutiveFailures   int32
+	LastError             sql.NullString
+	LastSucceededAt       sql.NullTime
+	NextFetchAt           sql.NullTime
+	Title                 sql.NullString
+	Description           sql.NullString
+	SiteUrl               sql.NullString
+	Language              sql.NullString
+	ImageUrl              sql.NullString
+	Generator             sql.NullString
+	DisabledAt            sql.NullTime
+	DisabledReason        sql.NullString
+	UserAgent             sql.NullString
+	RequestHeaders        []string
+	AuthUsername          sql.NullString
+	AuthPasswordSecret    sql.NullString
+	TtlSeconds            int32
+	UpdateIntervalSeconds int32
+	SkipHours             []int32
+	SkipDays              []int32
 }
 
 type FeedFollow struct {
@@ -30,14 +74,17 @@ type FeedFollow struct {
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Guid            string
+	DescriptionText string
+	Author          string
 }
 
 type User struct {
@@ -32,8 +32,15 @@ func (f CreateFeedFollowRow) String() string {
 }
 
 func (f GetFeedFollowsForUserRow) String() string {
-	return fmt.Sprintf(`* FeedID   : %v
-* FeedName : %v
-* Username : %v
-`, f.FeedID, f.FeedName, f.UserName)
+	return fmt.Sprintf(`* FeedID      : %v
+* FeedName    : %v
+* Username    : %v
+* Title       : %v
+* Description : %v
+* SiteUrl     : %v
+* Language    : %v
+* ImageUrl    : %v
+* Generator   : %v
+`, f.FeedID, f.FeedName, f.UserName, f.Title.String, f.Description.String, f.SiteUrl.String,
+		f.Language.String, f.ImageUrl.String, f.Generator.String)
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// JSONFeed models the subset of a JSON Feed 1.0/1.1 document (https://jsonfeed.org)
+// that gator cares about. Like Atom, JSON feeds are converted to RSSFeed right after
+// decoding.
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            jsonFeedID           `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+	// Author was deprecated in version 1.1 in favor of Authors
+	Author *JSONFeedAuthor `json:"author"`
+}
+
+// jsonFeedID is the id of a JSON feed item. It should be a string, but JSON Feed 1.1
+// asks readers to accept other values (numbers, mostly) and convert them to strings.
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = jsonFeedID(s)
+		return nil
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case nil:
+		*id = ""
+	case json.Number:
+		*id = jsonFeedID(value.String())
+	case bool:
+		*id = jsonFeedID(strconv.FormatBool(value))
+	default:
+		return fmt.Errorf("unsupported JSON feed item id %s", data)
+	}
+	return nil
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// isJSONFeed reports whether a response body should be decoded as a JSON feed. It
+// trusts the Content-Type header when it announces JSON and otherwise sniffs the body
+// looking for a leading '{', given that servers often label JSON feeds as text/plain
+// or application/octet-stream.
+func isJSONFeed(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "application/feed+json" || mediaType == "application/json" {
+			return true
+		}
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+}
+
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+	var feed JSONFeed
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("decoding JSON feed: %w", err)
+	}
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("decoding JSON feed: unknown version %q", feed.Version)
+	}
+	rss := feed.toRSS()
+	rss.Format = "JSON Feed " + strings.TrimPrefix(feed.Version, "https://jsonfeed.org/version/")
+	return rss, nil
+}
+
+// toRSS maps the JSON feed into the RSS feed model used by scrapeFeeds. Items prefer
+// their HTML content over the plain text one, and fall back to their external URL or
+// their ID (which is often a permalink) when they don't declare a URL.
+func (j *JSONFeed) toRSS() *RSSFeed {
+	var rss RSSFeed
+	rss.Channel.Title = j.Title
+	rss.Channel.Link = j.HomePageURL
+	rss.Channel.Description = j.Description
+	rss.Channel.Language = j.Language
+	rss.Channel.Image.URL = cmp.Or(j.Icon, j.Favicon)
+
+	for _, item := range j.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		if link == "" && strings.HasPrefix(string(item.ID), "http") {
+			link = string(item.ID)
+		}
+
+		// descriptions are HTML, while content_text and summary are plain text
+		description := item.ContentHTML
+		if description == "" {
+			description = html.EscapeString(item.ContentText)
+		}
+		if description == "" {
+			description = html.EscapeString(item.Summary)
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		authors := item.Authors
+		if len(authors) == 0 && item.Author != nil {
+			authors = []JSONFeedAuthor{*item.Author}
+		}
+		var names []string
+		for _, author := range authors {
+			if author.Name != "" {
+				names = append(names, author.Name)
+			}
+		}
+
+		var attachments []MediaContent
+		for _, attachment := range item.Attachments {
+			attachments = append(attachments, MediaContent{
+				URL:      attachment.URL,
+				Type:     attachment.MimeType,
+				FileSize: strconv.FormatInt(attachment.SizeInBytes, 10),
+				Duration: strconv.FormatFloat(attachment.DurationInSeconds, 'f', -1, 64),
+			})
+		}
+
+		rss.Channel.Item = append(rss.Channel.Item, RSSItem{
+			GUID:         string(item.ID),
+			Title:        item.Title,
+			Link:         link,
+			Description:  description,
+			PubDate:      pubDate,
+			Author:       strings.Join(names, ", "),
+			MediaContent: attachments,
+		})
+	}
+
+	return &rss
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestParseJSONFeedItemIDs(t *testing.T) {
+	body := []byte(`{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Example",
+		"items": [
+			{"id": "https://example.com/1", "url": "https://example.com/1", "content_text": "string"},
+			{"id": 2, "url": "https://example.com/2", "content_text": "integer"},
+			{"id": 3.50, "url": "https://example.com/3", "content_text": "float"},
+			{"id": null, "url": "https://example.com/4", "content_text": "null"},
+			{"url": "https://example.com/5", "content_text": "missing"}
+		]
+	}`)
+	feed, err := parseJSONFeed(body)
+	if err != nil {
+		t.Fatalf("parseJSONFeed() returned error: %v", err)
+	}
+
+	want := []string{"https://example.com/1", "2", "3.50", "", ""}
+	if len(feed.Channel.Item) != len(want) {
+		t.Fatalf("parseJSONFeed() returned %d items, want %d", len(feed.Channel.Item), len(want))
+	}
+	for i, item := range feed.Channel.Item {
+		if item.GUID != want[i] {
+			t.Errorf("item %d has GUID %q, want %q", i, item.GUID, want[i])
+		}
+	}
+
+	if _, err := parseJSONFeed([]byte(`{"version": "https://jsonfeed.org/version/1.1", "items": [{"id": {}}]}`)); err == nil {
+		t.Errorf("parseJSONFeed() accepted an object as item id")
+	}
+}
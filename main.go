@@ -16,8 +16,10 @@ import (
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db      *database.Queries
+	sqlDB   *sql.DB
+	cfg     *config.Config
+	fetcher *fetcher
 }
 
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
@@ -32,6 +34,25 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 	}
 }
 
+// middlewareFetcher prepares the HTTP client used to fetch feeds and media files before
+// running handler. Only the commands making requests go through it, so that a mistake
+// in the fetch settings doesn't break the rest of them.
+func middlewareFetcher(handler func(s *state, cmd command) error) func(*state, command) error {
+	return func(s *state, cmd command) error {
+		settings, err := s.cfg.FetchSettings()
+		if err != nil {
+			return fmt.Errorf("reading fetch settings: %w", err)
+		}
+
+		s.fetcher, err = newFetcher(settings)
+		if err != nil {
+			return fmt.Errorf("preparing the HTTP client: %w", err)
+		}
+
+		return handler(s, cmd)
+	}
+}
+
 func main() {
 	cfg, err := config.Read()
 	if err != nil {
@@ -47,8 +68,9 @@ func main() {
 	dbQueries := database.New(db)
 
 	s := &state{
-		db:  dbQueries,
-		cfg: &cfg,
+		db:    dbQueries,
+		sqlDB: db,
+		cfg:   &cfg,
 	}
 
 	c := commands{
@@ -63,9 +85,9 @@ func main() {
 	// list all registered users
 	c.register("users", handleListUsers)
 	// starts the infinite fetching loop of feeds
-	c.register("agg", handlerAgg)
+	c.register("agg", middlewareFetcher(handlerAgg))
 	// add and follow a feed
-	c.register("addfeed", middlewareLoggedIn(handlerAddFeed))
+	c.register("addfeed", middlewareFetcher(middlewareLoggedIn(handlerAddFeed)))
 	// list all registered feeds
 	c.register("feeds", handlerListAllFeeds)
 	// follow a registered feed
@@ -76,6 +98,12 @@ func main() {
 	c.register("unfollow", middlewareLoggedIn(handlerUnfollowFeeds))
 	// print post titles from feeds followed by active user
 	c.register("browse", middlewareLoggedIn(handlerBrowse))
+	// show a single post from the feeds followed by current user
+	c.register("read", middlewareLoggedIn(handlerRead))
+	// change how a feed added by current user is fetched
+	c.register("feedconfig", middlewareLoggedIn(handlerFeedConfig))
+	// download media files attached to posts from feeds followed by active user
+	c.register("download", middlewareFetcher(middlewareLoggedIn(handlerDownload)))
 
 	cliArgs := os.Args
 	if len(cliArgs) < 2 {
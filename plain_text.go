@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// textBlock is a paragraph of text. Consecutive tight blocks, like the items of a
+// list, aren't separated by blank lines.
+type textBlock struct {
+	text  string
+	tight bool
+}
+
+// textRenderer renders HTML as plain text: block elements become paragraphs, list
+// items are prefixed with bullets or numbers, quotes with "> " and links are turned
+// into numbered footnotes listed at the end.
+type textRenderer struct {
+	// width is the number of columns paragraphs are wrapped to, or 0 to leave them
+	// unwrapped
+	width  int
+	blocks []textBlock
+	inline strings.Builder
+	links  []string
+	// quote and indent prefix every line of the blocks, while marker prefixes only
+	// the first line of the next block
+	quote  string
+	indent string
+	marker string
+	tight  bool
+	pre    bool
+}
+
+// minWrapWidth is the narrowest column paragraphs are wrapped to, however deeply they
+// are nested in lists and quotes.
+const minWrapWidth = 20
+
+// plainText renders the given (sanitized) HTML nodes as plain text.
+func plainText(nodes []*html.Node) string {
+	return wrappedText(nodes, 0)
+}
+
+// wrappedText renders the given (sanitized) HTML nodes as plain text like plainText,
+// wrapping paragraphs to width columns. Continuation lines keep the quote markers and
+// indentation of their paragraph, while preformatted text and the footnotes listing
+// links are left as they are.
+func wrappedText(nodes []*html.Node, width int) string {
+	r := textRenderer{width: width}
+	for _, node := range nodes {
+		r.walk(node)
+	}
+	r.flush()
+
+	var b strings.Builder
+	for i, block := range r.blocks {
+		switch {
+		case i == 0:
+		case block.tight && r.blocks[i-1].tight:
+			b.WriteString("\n")
+		default:
+			b.WriteString("\n\n")
+		}
+		b.WriteString(block.text)
+	}
+	if len(r.links) > 0 {
+		b.WriteString("\n")
+		for i, link := range r.links {
+			fmt.Fprintf(&b, "\n[%d]: %v", i+1, link)
+		}
+	}
+	return b.String()
+}
+
+func (r *textRenderer) walk(node *html.Node) {
+	if node.Type == html.TextNode {
+		if r.pre {
+			r.inline.WriteString(node.Data)
+		} else {
+			r.writeCollapsed(node.Data)
+		}
+		return
+	}
+	if node.Type != html.ElementNode {
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Br:
+		r.inline.WriteString("\n")
+	case atom.Hr:
+		r.flush()
+		r.blocks = append(r.blocks, textBlock{text: r.quote + r.indent + "----"})
+	case atom.Img:
+		if alt := strings.TrimSpace(attribute(node, "alt")); alt != "" {
+			r.writeCollapsed("[image: " + alt + "]")
+		}
+	case atom.A:
+		r.walkChildren(node)
+		href := strings.TrimSpace(attribute(node, "href"))
+		if href == "" || strings.TrimPrefix(href, "mailto:") == strings.TrimSpace(textContent(node)) {
+			return
+		}
+		r.links = append(r.links, href)
+		fmt.Fprintf(&r.inline, " [%d]", len(r.links))
+	case atom.Ul, atom.Ol:
+		r.walkList(node)
+	case atom.Blockquote:
+		r.flush()
+		quote := r.quote
+		r.quote += "> "
+		r.walkChildren(node)
+		r.flush()
+		r.quote = quote
+	case atom.Pre:
+		r.flush()
+		r.pre = true
+		r.walkChildren(node)
+		r.flush()
+		r.pre = false
+	case atom.Td, atom.Th:
+		r.walkChildren(node)
+		r.inline.WriteString(" ")
+	case atom.P, atom.Div, atom.Figure, atom.Figcaption, atom.Table, atom.Tr, atom.Dl, atom.Dt, atom.Dd,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.flush()
+		r.walkChildren(node)
+		r.flush()
+	default:
+		r.walkChildren(node)
+	}
+}
+
+func (r *textRenderer) walkChildren(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+// walkList renders the items of an ordered or unordered list, indenting nested lists.
+func (r *textRenderer) walkList(list *html.Node) {
+	r.flush()
+	indent, tight := r.indent, r.tight
+	if tight {
+		// nested lists are indented below the item that holds them
+		r.indent += "   "
+	}
+	r.tight = true
+
+	number := 1
+	if start, err := strconv.Atoi(attribute(list, "start")); err == nil {
+		number = start
+	}
+	for item := list.FirstChild; item != nil; item = item.NextSibling {
+		if item.DataAtom != atom.Li {
+			r.walk(item)
+			continue
+		}
+		r.marker = "- "
+		if list.DataAtom == atom.Ol {
+			r.marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		r.walkChildren(item)
+		r.flush()
+	}
+
+	r.indent, r.tight = indent, tight
+	r.marker = ""
+}
+
+// writeCollapsed writes text to the current paragraph collapsing runs of whitespace
+// into single spaces, as browsers do.
+func (r *textRenderer) writeCollapsed(text string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		if text != "" {
+			r.inline.WriteString(" ")
+		}
+		return
+	}
+	if strings.TrimLeft(text, " \t\n\r\f") != text {
+		r.inline.WriteString(" ")
+	}
+	r.inline.WriteString(strings.Join(fields, " "))
+	if strings.TrimRight(text, " \t\n\r\f") != text {
+		r.inline.WriteString(" ")
+	}
+}
+
+// flush turns the text written so far into a block, prefixing its lines with the
+// current quote, indentation and list marker.
+func (r *textRenderer) flush() {
+	text := r.inline.String()
+	r.inline.Reset()
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if !r.pre {
+			line = strings.TrimSpace(line)
+		}
+		if line != "" || r.pre {
+			lines = append(lines, line)
+		}
+	}
+	if r.pre {
+		// only the blank lines surrounding preformatted text are dropped
+		for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+			lines = lines[1:]
+		}
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	hanging := strings.Repeat(" ", len(r.marker))
+	var prefixed []string
+	for i, line := range lines {
+		chunks := []string{line}
+		if r.width > 0 && !r.pre {
+			width := r.width - utf8.RuneCountInString(r.quote+r.indent+hanging)
+			chunks = wrapLine(line, max(width, minWrapWidth))
+		}
+		for j, chunk := range chunks {
+			prefix := r.quote + r.indent + hanging
+			if i == 0 && j == 0 {
+				prefix = r.quote + r.indent + r.marker
+			}
+			prefixed = append(prefixed, strings.TrimRight(prefix+chunk, " "))
+		}
+	}
+	r.marker = ""
+	r.blocks = append(r.blocks, textBlock{text: strings.Join(prefixed, "\n"), tight: r.tight})
+}
+
+// wrapLine splits a line of text into lines of at most width characters, breaking it
+// between words. Words longer than width are left whole.
+func wrapLine(line string, width int) []string {
+	var lines []string
+	var current strings.Builder
+	length := 0
+	for _, word := range strings.Fields(line) {
+		wordLength := utf8.RuneCountInString(word)
+		if length > 0 && length+1+wordLength > width {
+			lines = append(lines, current.String())
+			current.Reset()
+			length = 0
+		}
+		if length > 0 {
+			current.WriteString(" ")
+			length++
+		}
+		current.WriteString(word)
+		length += wordLength
+	}
+	if length > 0 || len(lines) == 0 {
+		lines = append(lines, current.String())
+	}
+	return lines
+}
+
+// textContent returns the text held by node and its descendants.
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// excerpt returns the first line of text, cut at a word boundary when it's longer than
+// limit characters.
+func excerpt(text string, limit int) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if utf8.RuneCountInString(line) <= limit {
+		return line
+	}
+	cut := string([]rune(line)[:limit])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// sanitizeDescription returns a safe version of the HTML description of a post along
+// with its rendering as plain text.
+func sanitizeDescription(description string) (string, string) {
+	nodes := sanitizeHTML(description)
+	return renderHTML(nodes), plainText(nodes)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWrappedText(t *testing.T) {
+	description := `<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor ` +
+		`<a href="https://example.com/a/very/long/url/that/must/not/be/wrapped">incididunt</a> ut labore.</p>` +
+		`<blockquote><p>Quoted text that goes on and on and on until it wraps.</p></blockquote>` +
+		`<ul><li>An item that is long enough to wrap around the width of the terminal.</li></ul>` +
+		"<pre>func main() {\n\tif   x  :=  compute(a,   b);   x > 0 {   // a comment longer than the width\n\t}\n}</pre>"
+
+	got := wrappedText(sanitizeHTML(description), 30)
+	want := `Lorem ipsum dolor sit amet,
+consectetur adipiscing elit,
+sed do eiusmod tempor
+incididunt [1] ut labore.
+
+> Quoted text that goes on and
+> on and on until it wraps.
+
+- An item that is long enough
+  to wrap around the width of
+  the terminal.
+
+func main() {
+	if   x  :=  compute(a,   b);   x > 0 {   // a comment longer than the width
+	}
+}
+
+[1]: https://example.com/a/very/long/url/that/must/not/be/wrapped`
+	if got != want {
+		t.Errorf("wrappedText() =\n%v\nwant\n%v", got, want)
+	}
+
+	if unwrapped := plainText(sanitizeHTML(description)); strings.Count(unwrapped, "\n") >= strings.Count(got, "\n") {
+		t.Errorf("plainText() wrapped the text:\n%v", unwrapped)
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  []string
+	}{
+		{line: "", width: 10, want: []string{""}},
+		{line: "short", width: 10, want: []string{"short"}},
+		{line: "exactly ten", width: 11, want: []string{"exactly ten"}},
+		{line: "one two three four", width: 9, want: []string{"one two", "three", "four"}},
+		{line: "a supercalifragilistic word", width: 10, want: []string{"a", "supercalifragilistic", "word"}},
+		{line: "ñandú über ñandú über", width: 10, want: []string{"ñandú über", "ñandú über"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := wrapLine(tt.line, tt.width); !slices.Equal(got, tt.want) {
+				t.Errorf("wrapLine(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+			}
+		})
+	}
+}
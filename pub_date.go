@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// pubDateLayouts lists the layouts tried by parsePubDate, in order. The most common
+// formats come first: RFC 1123 as mandated by RSS 2.0 and RFC 3339 as mandated by Atom
+// and JSON Feed. The rest are variants found in the wild. Weekdays are stripped and
+// zone names are rewritten as numeric offsets before trying these layouts, so none of
+// them includes a weekday or a zone name.
+var pubDateLayouts = []string{
+	"02 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 January 2006",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006",
+	"02-Jan-06 15:04:05 -0700",
+	"Jan 2 15:04:05 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"January 2, 2006 15:04:05 -0700",
+	"January 2, 2006",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// zoneOffsets maps the zone names allowed by RFC 822 (plus a few others commonly
+// found in feeds) to their offsets. Go can't be trusted with zone names because it
+// only knows the ones used by the local time zone and assumes UTC for the rest.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"IST":  "+0530",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+}
+
+// parsePubDate parses the publication date of a post trying every layout listed in
+// pubDateLayouts. It returns the parsed time in UTC, given that the database stores
+// timestamps without a time zone, or the fallback time if the date is missing or it
+// couldn't be parsed.
+func parsePubDate(date string, fallback time.Time) time.Time {
+	date = normalizePubDate(date)
+	if date == "" {
+		return fallback
+	}
+
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.UTC()
+		}
+	}
+
+	return fallback
+}
+
+// normalizePubDate collapses whitespace, drops the leading weekday (which is
+// redundant and often misspelled or in the wrong language) and any trailing comment
+// like "(UTC)", and replaces a trailing zone name with its numeric offset.
+func normalizePubDate(date string) string {
+	fields := strings.Fields(date)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	if first := strings.TrimSuffix(fields[0], ","); isLetters(first) && len(fields) > 1 {
+		if _, err := time.Parse("Jan", first); err != nil {
+			if _, err := time.Parse("January", first); err != nil {
+				fields = fields[1:]
+			}
+		}
+	}
+
+	// comments may span several fields, as in "(Coordinated Universal Time)"
+	if strings.HasSuffix(fields[len(fields)-1], ")") {
+		for i := len(fields) - 1; i > 0; i-- {
+			if strings.HasPrefix(fields[i], "(") {
+				fields = fields[:i]
+				break
+			}
+		}
+	}
+
+	last := fields[len(fields)-1]
+	if offset, ok := zoneOffsets[strings.ToUpper(last)]; ok && len(fields) > 1 {
+		fields[len(fields)-1] = offset
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePubDate(t *testing.T) {
+	fallback := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		date string
+		want time.Time
+	}{
+		{
+			name: "RFC 1123 with numeric offset",
+			date: "Mon, 02 Jan 2006 15:04:05 -0700",
+			want: time.Date(2006, time.January, 2, 22, 4, 5, 0, time.UTC),
+		},
+		{
+			name: "RFC 1123 with GMT",
+			date: "Tue, 10 Jun 2003 04:00:00 GMT",
+			want: time.Date(2003, time.June, 10, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "RFC 1123 with US zone name",
+			date: "Wed, 15 Mar 2023 09:30:00 EST",
+			want: time.Date(2023, time.March, 15, 14, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "RFC 1123 with lowercase zone name",
+			date: "Sat, 01 Jul 2023 12:00:00 pdt",
+			want: time.Date(2023, time.July, 1, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "wrong weekday",
+			date: "Fri, 02 Jan 2006 15:04:05 +0000",
+			want: time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name: "missing weekday",
+			date: "02 Jan 2006 15:04:05 +0100",
+			want: time.Date(2006, time.January, 2, 14, 4, 5, 0, time.UTC),
+		},
+		{
+			name: "single-digit day",
+			date: "Thu, 5 Oct 2023 08:00:00 +0000",
+			want: time.Date(2023, time.October, 5, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "two-digit year without seconds",
+			date: "Sun, 8 Jan 23 10:15 GMT",
+			want: time.Date(2023, time.January, 8, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "full month name",
+			date: "14 February 2024 18:00:00 +0000",
+			want: time.Date(2024, time.February, 14, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "extra whitespace",
+			date: "  Mon,  02 Jan 2006\n 15:04:05   +0000 ",
+			want: time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name: "trailing comment",
+			date: "Mon, 02 Jan 2006 15:04:05 +0000 (UTC)",
+			want: time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name: "trailing comment with spaces",
+			date: "Mon, 02 Jan 2006 15:04:05 -0500 (Eastern Standard Time)",
+			want: time.Date(2006, time.January, 2, 20, 4, 5, 0, time.UTC),
+		},
+		{
+			name: "RFC 3339",
+			date: "2024-03-10T12:30:00Z",
+			want: time.Date(2024, time.March, 10, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "RFC 3339 with fractional seconds and offset",
+			date: "2024-03-10T12:30:00.123456+02:00",
+			want: time.Date(2024, time.March, 10, 10, 30, 0, 123456000, time.UTC),
+		},
+		{
+			name: "ISO 8601 without zone",
+			date: "2024-03-10T12:30:00",
+			want: time.Date(2024, time.March, 10, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "ISO 8601 without seconds",
+			date: "2024-03-10T12:30Z",
+			want: time.Date(2024, time.March, 10, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "dc:date",
+			date: "2002-10-02T10:00:00-05:00",
+			want: time.Date(2002, time.October, 2, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "ISO 8601 with offset without colon",
+			date: "2024-03-05T10:00:00+0100",
+			want: time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "date only",
+			date: "2024-03-10",
+			want: time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "SQL-like timestamp",
+			date: "2024-03-10 12:30:00",
+			want: time.Date(2024, time.March, 10, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "US style",
+			date: "March 10, 2024",
+			want: time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "full month name without time",
+			date: "5 March 2024",
+			want: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "missing date",
+			date: "",
+			want: fallback,
+		},
+		{
+			name: "whitespace only",
+			date: "  \n ",
+			want: fallback,
+		},
+		{
+			name: "garbage",
+			date: "yesterday at noon",
+			want: fallback,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePubDate(tt.date, fallback); !got.Equal(tt.want) {
+				t.Errorf("parsePubDate(%q) = %v, want %v", tt.date, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePubDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{date: "Mon, 02 Jan 2006 15:04:05 GMT", want: "02 Jan 2006 15:04:05 +0000"},
+		{date: "Monday, 02 Jan 2006 15:04:05 CEST", want: "02 Jan 2006 15:04:05 +0200"},
+		{date: "Jan 2 15:04:05 2006", want: "Jan 2 15:04:05 2006"},
+		{date: "January 2, 2006", want: "January 2, 2006"},
+		{date: "02 Jan 2006 15:04:05 +0000 (Coordinated Universal Time)", want: "02 Jan 2006 15:04:05 +0000"},
+		{date: "02 Jan 2006 15:04:05 -0700 (MST)", want: "02 Jan 2006 15:04:05 -0700"},
+		{date: "2006-01-02T15:04:05Z", want: "2006-01-02T15:04:05Z"},
+		{date: "Z", want: "Z"},
+		{date: " \t", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			if got := normalizePubDate(tt.date); got != tt.want {
+				t.Errorf("normalizePubDate(%q) = %q, want %q", tt.date, got, tt.want)
+			}
+		})
+	}
+}
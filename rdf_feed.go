@@ -0,0 +1,52 @@
+package main
+
+// RDFFeed models the subset of an RSS 1.0 document (http://web.resource.org/rss/1.0/)
+// that gator cares about. Unlike RSS 2.0, the items are siblings of the channel, and
+// their publication date, when present, comes from the Dublin Core module.
+type RDFFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		// UpdatePeriod and UpdateFrequency come from the syndication module
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Image RSSImage  `xml:"image"`
+	Item  []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// toRSS maps the RDF feed into the RSS feed model used by scrapeFeeds.
+func (r *RDFFeed) toRSS() *RSSFeed {
+	var rss RSSFeed
+	rss.Channel.Title = r.Channel.Title
+	rss.Channel.Link = r.Channel.Link
+	rss.Channel.Description = r.Channel.Description
+	rss.Channel.Language = r.Channel.Language
+	rss.Channel.Image = r.Image
+	rss.Channel.UpdatePeriod = r.Channel.UpdatePeriod
+	rss.Channel.UpdateFrequency = r.Channel.UpdateFrequency
+
+	for _, item := range r.Item {
+		rss.Channel.Item = append(rss.Channel.Item, RSSItem{
+			GUID:        item.About,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     item.Date,
+			Author:      item.Creator,
+		})
+	}
+
+	return &rss
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"gator/internal/database"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// refreshHints gathers what a feed and its server tell us about how often the feed
+// should be fetched.
+type refreshHints struct {
+	// TTL comes from the RSS <ttl> element
+	TTL time.Duration
+	// UpdateInterval comes from the sy:updatePeriod and sy:updateFrequency elements
+	UpdateInterval time.Duration
+	// MaxAge comes from the max-age directive of the Cache-Control header
+	MaxAge time.Duration
+	// SkipHours and SkipDays come from the RSS <skipHours> and <skipDays> elements,
+	// and are expressed in GMT
+	SkipHours map[int]bool
+	SkipDays  map[time.Weekday]bool
+}
+
+// refreshHints extracts the refresh hints declared in the feed. maxAge is the value
+// of the max-age directive of the Cache-Control header of the response.
+func (r *RSSFeed) refreshHints(maxAge time.Duration) refreshHints {
+	hints := refreshHints{
+		MaxAge:         maxAge,
+		UpdateInterval: updateInterval(r.Channel.UpdatePeriod, r.Channel.UpdateFrequency),
+		SkipHours:      make(map[int]bool),
+		SkipDays:       make(map[time.Weekday]bool),
+	}
+
+	if ttl, err := strconv.Atoi(strings.TrimSpace(r.Channel.TTL)); err == nil && ttl > 0 {
+		hints.TTL = time.Duration(ttl) * time.Minute
+	}
+
+	for _, hour := range r.Channel.SkipHours {
+		// RSS allows both 0 and 24 for midnight
+		if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && h >= 0 && h <= 24 {
+			hints.SkipHours[h%24] = true
+		}
+	}
+
+	for _, day := range r.Channel.SkipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				hints.SkipDays[weekday] = true
+			}
+		}
+	}
+
+	return hints
+}
+
+// storedRefreshHints rebuilds the refresh hints stored along with a feed the last time
+// it was fetched, since a "304 Not Modified" response doesn't come with a feed to read
+// them from. maxAge is the value of the max-age directive of the Cache-Control header
+// of the response.
+func storedRefreshHints(feed database.ClaimFeedsToFetchRow, maxAge time.Duration) refreshHints {
+	hints := refreshHints{
+		TTL:            time.Duration(feed.TtlSeconds) * time.Second,
+		UpdateInterval: time.Duration(feed.UpdateIntervalSeconds) * time.Second,
+		MaxAge:         maxAge,
+		SkipHours:      make(map[int]bool),
+		SkipDays:       make(map[time.Weekday]bool),
+	}
+	for _, hour := range feed.SkipHours {
+		hints.SkipHours[int(hour)] = true
+	}
+	for _, day := range feed.SkipDays {
+		hints.SkipDays[time.Weekday(day)] = true
+	}
+	return hints
+}
+
+// storedParams arranges the hints declared in a feed in the columns expected by
+// UpdateFeedRefreshHints. The Cache-Control header is left out, given that it's sent
+// along with every response, including the "304 Not Modified" ones.
+func (h refreshHints) storedParams(feedID uuid.UUID) database.UpdateFeedRefreshHintsParams {
+	params := database.UpdateFeedRefreshHintsParams{
+		TtlSeconds:            seconds(h.TTL),
+		UpdateIntervalSeconds: seconds(h.UpdateInterval),
+		SkipHours:             []int32{},
+		SkipDays:              []int32{},
+		ID:                    feedID,
+	}
+	for hour := range h.SkipHours {
+		params.SkipHours = append(params.SkipHours, int32(hour))
+	}
+	for day := range h.SkipDays {
+		params.SkipDays = append(params.SkipDays, int32(day))
+	}
+	slices.Sort(params.SkipHours)
+	slices.Sort(params.SkipDays)
+	return params
+}
+
+// seconds converts d into a number of seconds that fits in an INTEGER column.
+func seconds(d time.Duration) int32 {
+	return int32(min(d/time.Second, math.MaxInt32))
+}
+
+// updateInterval converts the values of sy:updatePeriod and sy:updateFrequency into
+// the interval between updates of the feed. The frequency is the number of updates
+// per period, and it defaults to 1. It returns zero if the period isn't valid.
+func updateInterval(period, frequency string) time.Duration {
+	periods := map[string]time.Duration{
+		"hourly":  time.Hour,
+		"daily":   24 * time.Hour,
+		"weekly":  7 * 24 * time.Hour,
+		"monthly": 30 * 24 * time.Hour,
+		"yearly":  365 * 24 * time.Hour,
+	}
+	interval, ok := periods[strings.ToLower(strings.TrimSpace(period))]
+	if !ok {
+		return 0
+	}
+
+	if f, err := strconv.Atoi(strings.TrimSpace(frequency)); err == nil && f > 0 {
+		interval /= time.Duration(f)
+	}
+
+	return interval
+}
+
+// nextFetchTime returns the earliest time at which the feed should be fetched again.
+// It waits for the longest of the intervals hinted by the feed, bounded by the given
+// minimum and maximum intervals, and then skips the hours and days the feed asked us
+// not to fetch it, as long as that doesn't take longer than the maximum interval.
+func (h refreshHints) nextFetchTime(now time.Time, minInterval, maxInterval time.Duration) time.Time {
+	interval := max(h.TTL, h.UpdateInterval, h.MaxAge)
+	interval = min(max(interval, minInterval), maxInterval)
+
+	next := now.Add(interval).UTC()
+	latest := now.Add(maxInterval).UTC()
+	for next.Before(latest) && (h.SkipHours[next.Hour()] || h.SkipDays[next.Weekday()]) {
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	if next.After(latest) {
+		return latest
+	}
+	return next
+}
+
+// maxAge returns the value of the max-age directive of a Cache-Control header, or
+// zero if there's none or the response shouldn't be cached at all.
+func maxAge(cacheControl string) time.Duration {
+	var age time.Duration
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+				age = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return age
+}
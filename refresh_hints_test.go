@@ -0,0 +1,46 @@
+package main
+
+import (
+	"gator/internal/database"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestStoredRefreshHints(t *testing.T) {
+	feed := &RSSFeed{}
+	feed.Channel.TTL = "90"
+	feed.Channel.UpdatePeriod = "daily"
+	feed.Channel.UpdateFrequency = "2"
+	feed.Channel.SkipHours = []string{"24", "3"}
+	feed.Channel.SkipDays = []string{"Saturday", "sunday"}
+	hints := feed.refreshHints(time.Hour)
+
+	id := uuid.New()
+	params := hints.storedParams(id)
+	if params.TtlSeconds != 90*60 || params.UpdateIntervalSeconds != 12*60*60 || params.ID != id {
+		t.Errorf("storedParams() = %+v, want a TTL of 90 minutes and an update interval of 12 hours", params)
+	}
+
+	// the hints stored on a 200 response schedule the fetch after a 304 response
+	// the same way, with the max-age of the latter
+	stored := storedRefreshHints(database.ClaimFeedsToFetchRow{
+		TtlSeconds:            params.TtlSeconds,
+		UpdateIntervalSeconds: params.UpdateIntervalSeconds,
+		SkipHours:             params.SkipHours,
+		SkipDays:              params.SkipDays,
+	}, time.Hour)
+	now := time.Date(2024, time.March, 8, 10, 0, 0, 0, time.UTC)
+	for _, maxInterval := range []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour} {
+		want := hints.nextFetchTime(now, 0, maxInterval)
+		if got := stored.nextFetchTime(now, 0, maxInterval); !got.Equal(want) {
+			t.Errorf("stored hints schedule the next fetch at %v with a maximum of %v, want %v", got, maxInterval, want)
+		}
+	}
+
+	// feeds fetched before the hints were stored have none
+	if got := storedRefreshHints(database.ClaimFeedsToFetchRow{}, 0).nextFetchTime(now, time.Minute, time.Hour); !got.Equal(now.Add(time.Minute)) {
+		t.Errorf("empty stored hints schedule the next fetch at %v, want %v", got, now.Add(time.Minute))
+	}
+}
@@ -1,35 +1,76 @@
 package main
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"database/sql"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"gator/internal/database"
 	"html"
-	"io"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type RSSFeed struct {
+	// Format names the format the feed was published in, since every format is
+	// converted to an RSSFeed
+	Format  string `xml:"-"`
 	Channel struct {
-		Title       string    `xml:"title"`
+		Title string `xml:"title"`
+		// the "_" namespace matches elements without a namespace (see the decoder's
+		// DefaultSpace in parseFeed), which keeps <atom:link> from overwriting <link>
 		Link        string    `xml:"_ link"`
 		Description string    `xml:"description"`
+		Language    string    `xml:"language"`
+		Generator   string    `xml:"generator"`
+		Image       RSSImage  `xml:"_ image"`
+		ITunesImage RSSImage  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		TTL         string    `xml:"ttl"`
+		SkipHours   []string  `xml:"skipHours>hour"`
+		SkipDays    []string  `xml:"skipDays>day"`
 		Item        []RSSItem `xml:"item"`
+		// UpdatePeriod and UpdateFrequency come from the syndication module
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
 	} `xml:"channel"`
 }
 
+// RSSImage holds the URL of a channel's image, which comes in a child element in RSS
+// feeds and in an attribute in iTunes podcast feeds.
+type RSSImage struct {
+	URL  string `xml:"url"`
+	Href string `xml:"href,attr"`
+}
+
+func (i RSSImage) String() string {
+	if i.URL != "" {
+		return strings.TrimSpace(i.URL)
+	}
+	return strings.TrimSpace(i.Href)
+}
+
 type RSSItem struct {
+	GUID        string `xml:"guid"`
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	DCDate      string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string `xml:"author"`
+	DCCreator   string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	// podcast metadata
+	Enclosures     []RSSEnclosure `xml:"enclosure"`
+	MediaContent   []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	ITunesDuration string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesEpisode  string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
 }
 
 func (r *RSSFeed) String() string {
@@ -46,94 +87,460 @@ func (r *RSSFeed) String() string {
 
 func (r *RSSItem) String() string {
 	return fmt.Sprintf(`  {
+    GUID        : %v,
     Title       : %v,
     Link        : %v,
     Description : %v,
-    PubDate     : %v
+    PubDate     : %v,
+    Author      : %v
   },
-  `, r.Title, r.Link, r.Description, r.PubDate)
+  `, r.GUID, r.Title, r.Link, r.Description, r.PubDate, r.Author)
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("building GET request to fetch feed: %w", err)
+// id returns the value that identifies the item within its feed: its GUID or, when
+// the feed doesn't provide one, its link (or its title as a last resort).
+func (r *RSSItem) id() string {
+	if guid := strings.TrimSpace(r.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(r.Link); link != "" {
+		return link
 	}
-	req.Header.Set("User-Agent", "gator")
+	return r.Title
+}
 
-	client := http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("making GET request to fetch %v: %w", feedURL, err)
+// errNotModified is returned by fetchFeed when the server answers a conditional GET
+// request with "304 Not Modified", meaning that the feed didn't change since we last
+// fetched it.
+var errNotModified = errors.New("feed not modified since last fetch")
+
+// cacheHeaders holds the caching headers returned by the server the last time we
+// fetched a feed. ETag and LastModified are validators, which are sent back in the
+// If-None-Match and If-Modified-Since headers to make conditional GET requests.
+// MaxAge comes from the Cache-Control header and tells us for how long the feed can
+// be considered fresh. MovedTo comes from the Location header of permanent redirects
+// and holds the URL the feed now lives at, if it moved.
+type cacheHeaders struct {
+	ETag         string
+	LastModified string
+	MaxAge       time.Duration
+	MovedTo      string
+}
+
+// fetchFeed downloads and decodes the feed located at feedURL, sending the given
+// headers along with the request. The request is made conditional when the cache
+// validators are non-empty, in which case fetchFeed may return errNotModified. It
+// also returns the caching headers sent by the server so the caller can store them
+// for the next fetch.
+func (f *fetcher) fetchFeed(ctx context.Context, feedURL string, header http.Header, cache cacheHeaders) (*RSSFeed, cacheHeaders, error) {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if cache.ETag != "" {
+		header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		header.Set("If-Modified-Since", cache.LastModified)
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	res, body, err := f.get(ctx, feedURL, header)
 	if err != nil {
-		return nil, fmt.Errorf("reading response body of GET request to %v: %w", feedURL, err)
+		return nil, cacheHeaders{}, err
 	}
 
-	reader := bytes.NewReader(body)
-	decoder := xml.NewDecoder(reader)
-	decoder.DefaultSpace = "_"
+	if res.StatusCode == http.StatusNotModified {
+		cache.MaxAge = maxAge(res.Header.Get("Cache-Control"))
+		cache.MovedTo = permanentURL(res)
+		return nil, cache, errNotModified
+	}
+
+	return decodeFeed(feedURL, res, body)
+}
 
-	var rss RSSFeed
-	if err := decoder.Decode(&rss); err != nil {
-		return nil, fmt.Errorf("decoding response body to GET request to %v: %w", feedURL, err)
+// decodeFeed decodes the feed in the body of the response to a GET request to feedURL
+// and returns it along with the caching headers sent by the server.
+func decodeFeed(feedURL string, res *http.Response, body []byte) (*RSSFeed, cacheHeaders, error) {
+	rss, err := parseFeed(res.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, cacheHeaders{}, fmt.Errorf("decoding response body to GET request to %v: %w", feedURL, err)
 	}
 
+	// titles are plain text, but feeds often escape them twice. Descriptions are HTML
+	// instead, so they're left alone lest escaped markup, like code samples, turns
+	// into actual markup
 	rss.Channel.Title = html.UnescapeString(rss.Channel.Title)
 	rss.Channel.Description = html.UnescapeString(rss.Channel.Description)
 	for i := range rss.Channel.Item {
 		rss.Channel.Item[i].Title = html.UnescapeString(rss.Channel.Item[i].Title)
-		rss.Channel.Item[i].Description = html.UnescapeString(rss.Channel.Item[i].Description)
 	}
 
-	return &rss, nil
+	newCache := cacheHeaders{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		MaxAge:       maxAge(res.Header.Get("Cache-Control")),
+		MovedTo:      permanentURL(res),
+	}
+
+	return rss, newCache, nil
 }
 
-func scrapeFeeds(s *state) error {
-	ctx := context.Background()
+// parseFeed decodes an RSS 2.0, RSS 1.0 (RDF), Atom 1.0 or JSON Feed document into an
+// RSSFeed. JSON feeds are detected by their content type or by sniffing the body, while
+// the format of XML feeds is detected by looking at the name of the root element.
+// Bodies in a charset other than UTF-8 are converted first (see toUTF8).
+func parseFeed(contentType string, body []byte) (*RSSFeed, error) {
+	body, converted, err := toUTF8(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSONFeed(contentType, body) {
+		return parseJSONFeed(body)
+	}
 
-	feed, err := s.db.GetNextFeedToFetch(ctx)
+	root, err := rootElement(body, converted)
 	if err != nil {
-		return fmt.Errorf("getting feed to fetch: %w", err)
+		return nil, err
+	}
+
+	decoder := newXMLDecoder(body, converted)
+	decoder.DefaultSpace = "_"
+
+	switch root.Local {
+	case "rss":
+		var rss RSSFeed
+		if err := decoder.Decode(&rss); err != nil {
+			return nil, fmt.Errorf("decoding RSS feed: %w", err)
+		}
+		rss.Format = "RSS 2.0"
+		return &rss, nil
+	case "feed":
+		var atom AtomFeed
+		if err := decoder.Decode(&atom); err != nil {
+			return nil, fmt.Errorf("decoding Atom feed: %w", err)
+		}
+		feed := atom.toRSS()
+		feed.Format = "Atom 1.0"
+		return feed, nil
+	case "RDF":
+		var rdf RDFFeed
+		if err := decoder.Decode(&rdf); err != nil {
+			return nil, fmt.Errorf("decoding RDF feed: %w", err)
+		}
+		feed := rdf.toRSS()
+		feed.Format = "RSS 1.0 (RDF)"
+		return feed, nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format with root element <%v>", root.Local)
+	}
+}
+
+// rootElement returns the name of the first element found in an XML document.
+func rootElement(body []byte, converted bool) (xml.Name, error) {
+	decoder := newXMLDecoder(body, converted)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("looking for the root element: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
 	}
+}
+
+// feedClaimLease is how long a claimed feed is kept from other aggregators. It only
+// has to outlast a fetch: when a process dies while fetching a feed, the feed is
+// fetched again once the lease expires.
+const feedClaimLease = 15 * time.Minute
 
-	// we mark the feed as fetched before fetching it to account for the chance that
-	// we encounter an error while making the GET request
+// scrapeFeeds claims the `workers` feeds that have gone the longest without being
+// fetched, fetches each one in its own goroutine, and stores their posts. A
+// problem with one feed doesn't stop the others from being fetched; all the errors
+// found are joined in the returned error.
+func scrapeFeeds(ctx context.Context, s *state, workers int) error {
+	// we mark the feeds as fetched before fetching them to account for the chance that
+	// we encounter an error while making the GET requests
 	// this way, we always store the time at which we attempted the fetch
-	if err := s.db.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
-		LastFetchedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, ID: feed.ID}); err != nil {
-		return fmt.Errorf("marking feed from %v as fetched: %w", feed.Url, err)
+	// marking the feeds is also how we claim them: the query skips the rows locked by
+	// other gator processes and pushes the next fetch of the ones it picks back by
+	// feedClaimLease, so other aggregators sharing the database leave them alone while
+	// they're being fetched. Recording the success or failure of the fetch schedules
+	// the actual next fetch
+	now := time.Now().UTC()
+	feeds, err := s.db.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+		LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+		Limit:         int32(workers),
+		NextFetchAt:   sql.NullTime{Time: now.Add(feedClaimLease), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("claiming feeds to fetch: %w", err)
 	}
 
-	xmlData, err := fetchFeed(ctx, feed.Url)
+	errs := make([]error, len(feeds))
+	var wg sync.WaitGroup
+	for i, feed := range feeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = scrapeFeed(ctx, s, feed)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// scrapeFeed fetches a single feed, which must have been already claimed, and stores
+// its posts in the database.
+func scrapeFeed(ctx context.Context, s *state, feed database.ClaimFeedsToFetchRow) error {
+	settings := feedRequestSettings{
+		UserAgent:      feed.UserAgent.String,
+		Headers:        feed.RequestHeaders,
+		Username:       feed.AuthUsername.String,
+		PasswordSecret: feed.AuthPasswordSecret.String,
+	}
+	header, err := settings.header(s.cfg)
 	if err != nil {
-		return fmt.Errorf("fetching feed from %v: %w", feed.Url, err)
+		return recordFeedFailure(ctx, s, feed, fmt.Errorf("preparing request: %w", err))
 	}
 
-	log.Printf("[OK] %v\n", xmlData.Channel.Title)
-	timestampFormat := "Mon, 02 Jan 2006 15:04:05 -0700"
-	timestamp := time.Now().UTC()
-	for _, post := range xmlData.Channel.Item {
-		pubDate, err := time.Parse(timestampFormat, post.PubDate)
+	xmlData, cache, err := s.fetcher.fetchFeed(ctx, feed.Url, header, cacheHeaders{
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+	})
+	if err != nil && !errors.Is(err, errNotModified) {
+		// feeds that are gone won't come back, so we stop fetching them
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGone {
+			if disableErr := s.db.DisableFeed(ctx, database.DisableFeedParams{
+				DisabledAt:     sql.NullTime{Time: time.Now().UTC(), Valid: true},
+				DisabledReason: sql.NullString{String: err.Error(), Valid: true},
+				ID:             feed.ID,
+			}); disableErr != nil {
+				return fmt.Errorf("disabling feed from %v: %w", feed.Url, disableErr)
+			}
+			return fmt.Errorf("feed from %v is gone and was disabled: %w", feed.Url, err)
+		}
+
+		return recordFeedFailure(ctx, s, feed, err)
+	}
+
+	if cache.MovedTo != "" && cache.MovedTo != feed.Url {
+		feedID, err := moveFeed(ctx, s, feed.ID, cache.MovedTo)
 		if err != nil {
-			pubDate = time.Time{}
+			return recordFeedFailure(ctx, s, feed, fmt.Errorf("moving feed to %v: %w", cache.MovedTo, err))
+		}
+		log.Printf("[MOVED] %v -> %v\n", feed.Url, cache.MovedTo)
+		if slices.Contains(s.cfg.NoProxy, feed.Url) {
+			log.Printf("[WARN] no_proxy lists %v, which no longer applies: list %v instead\n", feed.Url, cache.MovedTo)
+		}
+		feed.ID, feed.Url = feedID, cache.MovedTo
+	}
+
+	// a "304 Not Modified" response doesn't come with a feed, so we schedule the next
+	// fetch with the hints stored the last time the feed was fetched
+	hints := storedRefreshHints(feed, cache.MaxAge)
+	if xmlData != nil {
+		hints = xmlData.refreshHints(cache.MaxAge)
+	}
+	minInterval, maxInterval, err := s.cfg.RefreshIntervals()
+	if err != nil {
+		return fmt.Errorf("scheduling next fetch of feed from %v: %w", feed.Url, err)
+	}
+	now := time.Now().UTC()
+	success := database.RecordFeedSuccessParams{
+		LastSucceededAt: sql.NullTime{Time: now, Valid: true},
+		NextFetchAt:     sql.NullTime{Time: hints.nextFetchTime(now, minInterval, maxInterval), Valid: true},
+		ID:              feed.ID,
+	}
+
+	if xmlData == nil {
+		if err := s.db.RecordFeedSuccess(ctx, success); err != nil {
+			return fmt.Errorf("recording successful fetch of feed from %v: %w", feed.Url, err)
 		}
-		if err := s.db.CreatePost(ctx, database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   timestamp,
-			UpdatedAt:   timestamp,
-			Title:       post.Title,
-			Url:         post.Link,
-			Description: post.Description,
-			PublishedAt: pubDate,
-			FeedID:      feed.ID,
-		}); err != nil {
-			log.Printf("%v\n", err)
+		log.Printf("[OK] %v (not modified)\n", feed.Url)
+		return nil
+	}
+
+	results, err := storeFeed(ctx, s, feed.ID, xmlData, cache, hints, success)
+	if err != nil {
+		return recordFeedFailure(ctx, s, feed, err)
+	}
+
+	// the query only returns the posts it inserted or updated, skipping the ones that
+	// were already stored and didn't change
+	var created int
+	for _, result := range results {
+		if result.Inserted {
+			created++
 		}
 	}
+	log.Printf("[OK] %v: %v new post(s), %v updated post(s)\n", xmlData.Channel.Title, created, len(results)-created)
 
 	return nil
 }
+
+// recordFeedFailure records why a feed couldn't be fetched or stored, so that it shows
+// in the health of the feed, and leaves the feed alone for a while, which grows
+// exponentially with every consecutive failure. It returns err along with the time of
+// the next attempt.
+func recordFeedFailure(ctx context.Context, s *state, feed database.ClaimFeedsToFetchRow, err error) error {
+	failures := feed.ConsecutiveFailures + 1
+	nextFetchAt := time.Now().UTC().Add(retryWait(failures, err))
+	if recordErr := s.db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		LastError:   sql.NullString{String: err.Error(), Valid: true},
+		NextFetchAt: sql.NullTime{Time: nextFetchAt, Valid: true},
+		ID:          feed.ID,
+	}); recordErr != nil {
+		log.Printf("[NOT OK] recording failure of feed from %v: %v\n", feed.Url, recordErr)
+	}
+	return fmt.Errorf("feed from %v failed (failure #%v, retrying after %v): %w",
+		feed.Url, failures, nextFetchAt.Format(time.DateTime), err)
+}
+
+// storeFeed stores the posts of a feed that was fetched successfully, along with its
+// metadata, cache validators and refresh hints, and records the success. It returns
+// the posts that were inserted or updated.
+//
+// Everything is stored in a single transaction, so a crash can't leave the feed half
+// ingested (or, worse, store the validators without the posts, which would make the
+// server tell us that we're up to date next time).
+func storeFeed(ctx context.Context, s *state, feedID uuid.UUID, xmlData *RSSFeed, cache cacheHeaders,
+	hints refreshHints, success database.RecordFeedSuccessParams) ([]database.UpsertPostsRow, error) {
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	if err := qtx.RecordFeedSuccess(ctx, success); err != nil {
+		return nil, fmt.Errorf("recording successful fetch: %w", err)
+	}
+
+	if err := qtx.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		Title:       nullString(xmlData.Channel.Title),
+		Description: nullString(xmlData.Channel.Description),
+		SiteUrl:     nullString(xmlData.Channel.Link),
+		Language:    nullString(xmlData.Channel.Language),
+		ImageUrl:    nullString(cmp.Or(xmlData.Channel.Image.String(), xmlData.Channel.ITunesImage.String())),
+		Generator:   nullString(xmlData.Channel.Generator),
+		ID:          feedID,
+	}); err != nil {
+		return nil, fmt.Errorf("storing metadata: %w", err)
+	}
+
+	if err := qtx.UpdateFeedCacheValidators(ctx, database.UpdateFeedCacheValidatorsParams{
+		Etag:         nullString(cache.ETag),
+		LastModified: nullString(cache.LastModified),
+		ID:           feedID,
+	}); err != nil {
+		return nil, fmt.Errorf("storing cache validators: %w", err)
+	}
+
+	if err := qtx.UpdateFeedRefreshHints(ctx, hints.storedParams(feedID)); err != nil {
+		return nil, fmt.Errorf("storing refresh hints: %w", err)
+	}
+
+	posts := postsBatch(feedID, success.LastSucceededAt.Time, xmlData.Channel.Item)
+	// posts stored before GUIDs were tracked got their URL as GUID, so they're given
+	// their actual GUID before upserting, lest they're stored a second time
+	if err := qtx.RekeyLegacyPosts(ctx, database.RekeyLegacyPostsParams{
+		Urls: posts.Urls, Guids: posts.Guids, FeedID: feedID,
+	}); err != nil {
+		return nil, fmt.Errorf("updating GUIDs of posts: %w", err)
+	}
+
+	results, err := qtx.UpsertPosts(ctx, posts)
+	if err != nil {
+		return nil, fmt.Errorf("storing posts: %w", err)
+	}
+
+	// enclosures are stored for every item, not only the posts that were inserted or
+	// updated, since media may be attached to a post after it was published
+	postIDs, err := qtx.GetPostIDsByGUID(ctx, database.GetPostIDsByGUIDParams{FeedID: feedID, Guids: posts.Guids})
+	if err != nil {
+		return nil, fmt.Errorf("getting posts: %w", err)
+	}
+	if err := qtx.UpsertEnclosures(ctx, enclosuresBatch(success.LastSucceededAt.Time, postIDs, xmlData.Channel.Item)); err != nil {
+		return nil, fmt.Errorf("storing enclosures: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return results, nil
+}
+
+// postsBatch arranges the items of a feed in the columns expected by UpsertPosts.
+// Items repeated in the feed are stored only once, given that Postgres refuses to
+// upsert the same row twice in a single statement.
+func postsBatch(feedID uuid.UUID, fetchedAt time.Time, items []RSSItem) database.UpsertPostsParams {
+	batch := database.UpsertPostsParams{FetchedAt: fetchedAt, FeedID: feedID}
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		guid := item.id()
+		if seen[guid] {
+			continue
+		}
+		seen[guid] = true
+
+		// posts without a valid publication date are considered to be published at
+		// the time we fetched them
+		pubDate := item.PubDate
+		if pubDate == "" {
+			pubDate = item.DCDate
+		}
+
+		batch.Ids = append(batch.Ids, uuid.New())
+		batch.Titles = append(batch.Titles, item.Title)
+		batch.Urls = append(batch.Urls, item.Link)
+		// descriptions are stored sanitized, along with their plain text rendering
+		description, text := sanitizeDescription(item.Description)
+		batch.Descriptions = append(batch.Descriptions, description)
+		batch.DescriptionTexts = append(batch.DescriptionTexts, text)
+		batch.PublishedAts = append(batch.PublishedAts, parsePubDate(pubDate, fetchedAt))
+		batch.Guids = append(batch.Guids, guid)
+		batch.Authors = append(batch.Authors, strings.TrimSpace(cmp.Or(item.Author, item.DCCreator)))
+	}
+	return batch
+}
+
+// enclosuresBatch arranges the enclosures of the items in the columns expected by
+// UpsertEnclosures, attaching them to the posts stored for the items.
+func enclosuresBatch(fetchedAt time.Time, posts []database.GetPostIDsByGUIDRow, items []RSSItem) database.UpsertEnclosuresParams {
+	postIDs := make(map[string]uuid.UUID, len(posts))
+	for _, post := range posts {
+		postIDs[post.Guid] = post.ID
+	}
+
+	batch := database.UpsertEnclosuresParams{FetchedAt: fetchedAt}
+	for _, item := range items {
+		postID, ok := postIDs[item.id()]
+		if !ok {
+			continue
+		}
+		// an item repeated in the feed is stored only once
+		delete(postIDs, item.id())
+
+		for _, e := range item.enclosures() {
+			batch.Ids = append(batch.Ids, uuid.New())
+			batch.PostIds = append(batch.PostIds, postID)
+			batch.Urls = append(batch.Urls, e.URL)
+			batch.MediaTypes = append(batch.MediaTypes, e.MediaType)
+			batch.Sizes = append(batch.Sizes, e.Size)
+			batch.Durations = append(batch.Durations, e.Duration)
+			batch.Episodes = append(batch.Episodes, e.Episode)
+		}
+	}
+	return batch
+}
+
+// nullString converts a string to a sql.NullString, mapping blank strings to NULL.
+func nullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
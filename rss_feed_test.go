@@ -0,0 +1,470 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gator/internal/config"
+	"gator/internal/database"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// testDatabase connects to the database set in $GATOR_TEST_DB_URL, which must be a
+// disposable database with every migration applied, and creates a user owning the
+// records made by the test, which are removed along with it when the test ends.
+// Tests needing a database are skipped when the variable isn't set.
+func testDatabase(tb testing.TB) (*sql.DB, *database.Queries, database.User) {
+	tb.Helper()
+	dbURL := os.Getenv("GATOR_TEST_DB_URL")
+	if dbURL == "" {
+		tb.Skip("GATOR_TEST_DB_URL isn't set")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		tb.Fatalf("opening test database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	queries := database.New(db)
+	now := time.Now().UTC()
+	user, err := queries.CreateUser(context.Background(), database.CreateUserParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "test-" + uuid.NewString(),
+	})
+	if err != nil {
+		tb.Fatalf("creating test user: %v", err)
+	}
+	tb.Cleanup(func() {
+		if _, err := db.Exec("DELETE FROM users WHERE id = $1", user.ID); err != nil {
+			tb.Errorf("deleting test user: %v", err)
+		}
+	})
+	return db, queries, user
+}
+
+// createTestFeeds stores n feeds owned by user and returns their IDs.
+func createTestFeeds(tb testing.TB, queries *database.Queries, user database.User, n int) map[uuid.UUID]bool {
+	tb.Helper()
+	now := time.Now().UTC()
+	ids := make(map[uuid.UUID]bool, n)
+	for i := range n {
+		feed, err := queries.CreateFeed(context.Background(), database.CreateFeedParams{
+			ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: user.ID,
+			Name: fmt.Sprintf("feed %d", i),
+			Url:  fmt.Sprintf("https://example.com/%v/feed/%d", user.ID, i),
+		})
+		if err != nil {
+			tb.Fatalf("creating test feed: %v", err)
+		}
+		ids[feed.ID] = true
+	}
+	return ids
+}
+
+func TestClaimFeedsToFetchInParallel(t *testing.T) {
+	_, queries, user := testDatabase(t)
+	ids := createTestFeeds(t, queries, user, 50)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	claim := func(at time.Time) ([]database.ClaimFeedsToFetchRow, error) {
+		return queries.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+			LastFetchedAt: sql.NullTime{Time: at, Valid: true},
+			Limit:         3,
+			NextFetchAt:   sql.NullTime{Time: at.Add(feedClaimLease), Valid: true},
+		})
+	}
+
+	var mu sync.Mutex
+	claims := make(map[uuid.UUID]int)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				feeds, err := claim(now)
+				if err != nil {
+					t.Errorf("claiming feeds: %v", err)
+					return
+				}
+				if len(feeds) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, feed := range feeds {
+					claims[feed.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for id := range ids {
+		if claims[id] != 1 {
+			t.Errorf("feed %v was claimed %d times, want 1", id, claims[id])
+		}
+	}
+
+	// the feeds are claimed again once their lease expires
+	expired := 0
+	for {
+		feeds, err := claim(now.Add(feedClaimLease + time.Second))
+		if err != nil {
+			t.Fatalf("claiming feeds: %v", err)
+		}
+		if len(feeds) == 0 {
+			break
+		}
+		for _, feed := range feeds {
+			if ids[feed.ID] {
+				expired++
+			}
+		}
+	}
+	if expired != len(ids) {
+		t.Errorf("%d feeds were claimed after their lease expired, want %d", expired, len(ids))
+	}
+}
+
+func TestRekeyLegacyPosts(t *testing.T) {
+	db, queries, user := testDatabase(t)
+	var feedID uuid.UUID
+	for id := range createTestFeeds(t, queries, user, 1) {
+		feedID = id
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	items := []RSSItem{
+		{Title: "First", Link: "https://example.com/first", GUID: "https://example.com/?p=1"},
+		{Title: "Second", Link: "https://example.com/second", GUID: "tag:example.com,2024:2"},
+		{Title: "Third", Link: "https://example.com/third"},
+	}
+
+	// posts stored before GUIDs were tracked had their URL copied as GUID
+	legacy := postsBatch(feedID, now, items)
+	legacy.Guids = legacy.Urls
+	if _, err := queries.UpsertPosts(ctx, legacy); err != nil {
+		t.Fatalf("storing legacy posts: %v", err)
+	}
+
+	posts := postsBatch(feedID, now, items)
+	if err := queries.RekeyLegacyPosts(ctx, database.RekeyLegacyPostsParams{
+		Urls: posts.Urls, Guids: posts.Guids, FeedID: feedID,
+	}); err != nil {
+		t.Fatalf("RekeyLegacyPosts() returned error: %v", err)
+	}
+	results, err := queries.UpsertPosts(ctx, posts)
+	if err != nil {
+		t.Fatalf("UpsertPosts() returned error: %v", err)
+	}
+	for _, result := range results {
+		if result.Inserted {
+			t.Errorf("post %v was stored a second time", result.Guid)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM posts WHERE feed_id = $1", feedID).Scan(&count); err != nil {
+		t.Fatalf("counting posts: %v", err)
+	}
+	if count != len(items) {
+		t.Errorf("feed has %d posts, want %d", count, len(items))
+	}
+}
+
+// BenchmarkUpsertPosts stores a 500-item feed in a single statement, as scrapeFeed
+// does, and one item at a time, as gator used to.
+func BenchmarkUpsertPosts(b *testing.B) {
+	_, queries, user := testDatabase(b)
+	var feedID uuid.UUID
+	for id := range createTestFeeds(b, queries, user, 1) {
+		feedID = id
+	}
+
+	ctx := context.Background()
+	run := 0
+	newItems := func() []RSSItem {
+		run++
+		items := make([]RSSItem, 500)
+		for i := range items {
+			link := fmt.Sprintf("https://example.com/%d/%d", run, i)
+			items[i] = RSSItem{
+				Title:       fmt.Sprintf("Post %d", i),
+				Link:        link,
+				GUID:        link,
+				Description: "<p>A <em>short</em> description with a <a href=\"" + link + "\">link</a>.</p>",
+				PubDate:     "Mon, 02 Jan 2006 15:04:05 GMT",
+			}
+		}
+		return items
+	}
+
+	b.Run("batch", func(b *testing.B) {
+		for range b.N {
+			batch := postsBatch(feedID, time.Now().UTC(), newItems())
+			if _, err := queries.UpsertPosts(ctx, batch); err != nil {
+				b.Fatalf("UpsertPosts() returned error: %v", err)
+			}
+		}
+	})
+	b.Run("one by one", func(b *testing.B) {
+		for range b.N {
+			for _, item := range newItems() {
+				batch := postsBatch(feedID, time.Now().UTC(), []RSSItem{item})
+				if _, err := queries.UpsertPosts(ctx, batch); err != nil {
+					b.Fatalf("UpsertPosts() returned error: %v", err)
+				}
+			}
+		}
+	})
+}
+
+func TestEnclosuresAttachedLater(t *testing.T) {
+	_, queries, user := testDatabase(t)
+	var feedID uuid.UUID
+	for id := range createTestFeeds(t, queries, user, 1) {
+		feedID = id
+	}
+
+	ctx := context.Background()
+	store := func(items []RSSItem) {
+		t.Helper()
+		now := time.Now().UTC()
+		posts := postsBatch(feedID, now, items)
+		if _, err := queries.UpsertPosts(ctx, posts); err != nil {
+			t.Fatalf("UpsertPosts() returned error: %v", err)
+		}
+		postIDs, err := queries.GetPostIDsByGUID(ctx, database.GetPostIDsByGUIDParams{FeedID: feedID, Guids: posts.Guids})
+		if err != nil {
+			t.Fatalf("GetPostIDsByGUID() returned error: %v", err)
+		}
+		if err := queries.UpsertEnclosures(ctx, enclosuresBatch(now, postIDs, items)); err != nil {
+			t.Fatalf("UpsertEnclosures() returned error: %v", err)
+		}
+	}
+
+	episode := RSSItem{Title: "Episode 1", Link: "https://example.com/1", GUID: "episode-1"}
+	store([]RSSItem{episode})
+
+	// the media file is attached once the post was already stored, which doesn't
+	// change the post itself
+	episode.Enclosures = []RSSEnclosure{{URL: "https://cdn.example.com/1.mp3", Length: "1000", Type: "audio/mpeg"}}
+	store([]RSSItem{episode})
+
+	postIDs, err := queries.GetPostIDsByGUID(ctx, database.GetPostIDsByGUIDParams{FeedID: feedID, Guids: []string{"episode-1"}})
+	if err != nil || len(postIDs) != 1 {
+		t.Fatalf("GetPostIDsByGUID() = %v, %v, want a single post", postIDs, err)
+	}
+	enclosures, err := queries.GetEnclosuresForPost(ctx, postIDs[0].ID)
+	if err != nil {
+		t.Fatalf("GetEnclosuresForPost() returned error: %v", err)
+	}
+	if len(enclosures) != 1 || enclosures[0].Url != "https://cdn.example.com/1.mp3" {
+		t.Errorf("GetEnclosuresForPost() = %+v, want the enclosure attached later", enclosures)
+	}
+}
+
+func TestScrapeFeedKeepsRefreshHintsWhenNotModified(t *testing.T) {
+	db, queries, user := testDatabase(t)
+
+	// the feed asks to be fetched every two hours, but never on Sundays
+	const body = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Hints</title><link>https://example.com/</link><ttl>120</ttl>
+<skipDays><day>Sunday</day></skipDays>
+<item><title>Post</title><link>https://example.com/1</link><guid>post-1</guid></item>
+</channel></rss>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	feed, err := queries.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: user.ID,
+		Name: "hints", Url: server.URL + "/" + user.ID.String(),
+	})
+	if err != nil {
+		t.Fatalf("creating test feed: %v", err)
+	}
+
+	s := &state{db: queries, sqlDB: db, cfg: &config.Config{}, fetcher: newTestFetcher(t, nil)}
+	// scrapeFeed is given the feed as claimed by ClaimFeedsToFetch, which would claim
+	// the feeds of other tests too
+	claimed := func() database.ClaimFeedsToFetchRow {
+		t.Helper()
+		row := database.ClaimFeedsToFetchRow{ID: feed.ID, Url: feed.Url}
+		if err := db.QueryRowContext(ctx, `SELECT etag, last_modified, consecutive_failures,
+			ttl_seconds, update_interval_seconds, skip_hours, skip_days FROM feeds WHERE id = $1`, feed.ID).Scan(
+			&row.Etag, &row.LastModified, &row.ConsecutiveFailures,
+			&row.TtlSeconds, &row.UpdateIntervalSeconds, pq.Array(&row.SkipHours), pq.Array(&row.SkipDays),
+		); err != nil {
+			t.Fatalf("getting test feed: %v", err)
+		}
+		return row
+	}
+	nextFetchAt := func() time.Time {
+		t.Helper()
+		var next time.Time
+		if err := db.QueryRowContext(ctx, "SELECT next_fetch_at FROM feeds WHERE id = $1", feed.ID).Scan(&next); err != nil {
+			t.Fatalf("getting next fetch of test feed: %v", err)
+		}
+		return next
+	}
+	// the next fetch is two hours away, pushed to Monday if that's a Sunday
+	assertHinted := func(fetchedAt time.Time) {
+		t.Helper()
+		next := nextFetchAt()
+		want := fetchedAt.Add(2 * time.Hour)
+		if next.Before(want) || next.Weekday() == time.Sunday {
+			t.Errorf("next fetch at %v, want two hours after %v and not on a Sunday", next, fetchedAt)
+		}
+		if want.Weekday() != time.Sunday && next.After(want.Add(time.Minute)) {
+			t.Errorf("next fetch at %v, want about two hours after %v", next, fetchedAt)
+		}
+	}
+
+	fetchedAt := time.Now().UTC()
+	if err := scrapeFeed(ctx, s, claimed()); err != nil {
+		t.Fatalf("first scrapeFeed() returned error: %v", err)
+	}
+	assertHinted(fetchedAt)
+
+	row := claimed()
+	if !row.Etag.Valid {
+		t.Fatalf("first scrapeFeed() didn't store the ETag")
+	}
+	fetchedAt = time.Now().UTC()
+	if err := scrapeFeed(ctx, s, row); err != nil {
+		t.Fatalf("second scrapeFeed() returned error: %v", err)
+	}
+	assertHinted(fetchedAt)
+}
+
+func TestScrapeFeedRecordsRequestFailures(t *testing.T) {
+	db, queries, user := testDatabase(t)
+	var feedID uuid.UUID
+	for id := range createTestFeeds(t, queries, user, 1) {
+		feedID = id
+	}
+
+	// the feed refers to a secret that can't be found, so it can't even be requested
+	ctx := context.Background()
+	if err := queries.UpdateFeedRequestSettings(ctx, database.UpdateFeedRequestSettingsParams{
+		AuthUsername:       sql.NullString{String: "gator", Valid: true},
+		AuthPasswordSecret: sql.NullString{String: "missing", Valid: true},
+		UpdatedAt:          time.Now().UTC(),
+		ID:                 feedID,
+	}); err != nil {
+		t.Fatalf("UpdateFeedRequestSettings() returned error: %v", err)
+	}
+
+	s := &state{db: queries, sqlDB: db, cfg: &config.Config{SecretsFile: t.TempDir() + "/secrets.json"}}
+	feed := database.ClaimFeedsToFetchRow{
+		ID: feedID, Url: "https://example.com/feed",
+		AuthUsername:       sql.NullString{String: "gator", Valid: true},
+		AuthPasswordSecret: sql.NullString{String: "missing", Valid: true},
+	}
+	start := time.Now().UTC()
+	if err := scrapeFeed(ctx, s, feed); err == nil {
+		t.Fatalf("scrapeFeed() succeeded without the secret of the feed")
+	}
+
+	var failures int32
+	var lastError sql.NullString
+	var nextFetchAt time.Time
+	if err := db.QueryRowContext(ctx, "SELECT consecutive_failures, last_error, next_fetch_at FROM feeds WHERE id = $1", feedID).Scan(
+		&failures, &lastError, &nextFetchAt,
+	); err != nil {
+		t.Fatalf("getting health of test feed: %v", err)
+	}
+	if failures != 1 || !lastError.Valid {
+		t.Errorf("feed has %d failure(s) and last error %q, want the failure to prepare the request", failures, lastError.String)
+	}
+	if nextFetchAt.Before(start.Add(feedBackoffBase)) {
+		t.Errorf("next fetch at %v, want it backed off by %v", nextFetchAt, feedBackoffBase)
+	}
+}
+
+func TestDecodeFeedKeepsEscapedMarkup(t *testing.T) {
+	const code = `<div class="x">hi</div>`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "Atom XHTML content",
+			contentType: "application/atom+xml",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><title>Code</title><entry>
+				<title>Snippet</title><id>1</id>
+				<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><pre>&lt;div class="x"&gt;hi&lt;/div&gt;</pre></div></content>
+				</entry></feed>`,
+		},
+		{
+			name:        "Atom HTML content",
+			contentType: "application/atom+xml",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><title>Code</title><entry>
+				<title>Snippet</title><id>1</id>
+				<content type="html">&lt;pre&gt;&amp;lt;div class="x"&amp;gt;hi&amp;lt;/div&amp;gt;&lt;/pre&gt;</content>
+				</entry></feed>`,
+		},
+		{
+			name:        "Atom text summary",
+			contentType: "application/atom+xml",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><title>Code</title><entry>
+				<title>Snippet</title><id>1</id>
+				<summary>&lt;div class="x"&gt;hi&lt;/div&gt;</summary>
+				</entry></feed>`,
+		},
+		{
+			name:        "RSS description in CDATA",
+			contentType: "application/rss+xml",
+			body: `<rss version="2.0"><channel><title>Code</title><item>
+				<title>Snippet</title><guid>1</guid>
+				<description><![CDATA[<pre>&lt;div class="x"&gt;hi&lt;/div&gt;</pre>]]></description>
+				</item></channel></rss>`,
+		},
+		{
+			name:        "JSON Feed text content",
+			contentType: "application/feed+json",
+			body: `{"version": "https://jsonfeed.org/version/1.1", "title": "Code",
+				"items": [{"id": "1", "title": "Snippet", "content_text": "<div class=\"x\">hi</div>"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{
+				Header:  http.Header{"Content-Type": {tt.contentType}},
+				Request: httptest.NewRequest(http.MethodGet, "https://example.com/feed", nil),
+			}
+			feed, _, err := decodeFeed("https://example.com/feed", res, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("decodeFeed() returned error: %v", err)
+			}
+			if len(feed.Channel.Item) != 1 {
+				t.Fatalf("decodeFeed() returned %d items, want 1", len(feed.Channel.Item))
+			}
+			if _, text := sanitizeDescription(feed.Channel.Item[0].Description); text != code {
+				t.Errorf("description %q renders as %q, want %q", feed.Channel.Item[0].Description, text, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedAttributes lists the elements kept by sanitizeHTML along with the attributes
+// they may keep. Elements missing from it are unwrapped (their children are kept)
+// unless they're listed in droppedElements.
+var allowedAttributes = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Cite:       nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          nil,
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedElements lists the elements removed by sanitizeHTML along with their
+// contents, since their contents aren't meant to be read.
+var droppedElements = map[atom.Atom]bool{
+	atom.Base:     true,
+	atom.Button:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Head:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Math:     true,
+	atom.Meta:     true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+}
+
+// sanitizeHTML parses an HTML fragment, like the description of a post, and returns
+// its nodes stripped of everything that isn't safe or useful to display: scripts,
+// styles, embedded content, event handlers, links with unsafe schemes and tracking
+// pixels.
+func sanitizeHTML(fragment string) []*html.Node {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		// the parser only fails when reading from the reader does
+		return []*html.Node{{Type: html.TextNode, Data: fragment}}
+	}
+
+	var sanitized []*html.Node
+	for _, node := range nodes {
+		sanitized = append(sanitized, sanitizeNode(node)...)
+	}
+	return sanitized
+}
+
+// sanitizeNode returns a sanitized copy of node, which may be several nodes when node
+// is unwrapped or none when it's dropped.
+func sanitizeNode(node *html.Node) []*html.Node {
+	switch node.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: node.Data}}
+	case html.ElementNode:
+	default:
+		// comments and doctypes
+		return nil
+	}
+
+	if droppedElements[node.DataAtom] {
+		return nil
+	}
+
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, sanitizeNode(child)...)
+	}
+
+	allowed, ok := allowedAttributes[node.DataAtom]
+	if !ok {
+		return children
+	}
+
+	clean := &html.Node{Type: html.ElementNode, Data: node.Data, DataAtom: node.DataAtom}
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" || !slices.Contains(allowed, attr.Key) {
+			continue
+		}
+		if attr.Key == "href" || attr.Key == "src" {
+			if !isSafeURL(attr.Val) {
+				continue
+			}
+		}
+		clean.Attr = append(clean.Attr, html.Attribute{Key: attr.Key, Val: attr.Val})
+	}
+
+	switch node.DataAtom {
+	case atom.A:
+		if attribute(clean, "href") != "" {
+			clean.Attr = append(clean.Attr, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+		}
+	case atom.Img:
+		if attribute(clean, "src") == "" || isTrackingPixel(clean) {
+			return nil
+		}
+	}
+
+	for _, child := range children {
+		clean.AppendChild(child)
+	}
+	return []*html.Node{clean}
+}
+
+// isSafeURL reports whether a link or an image source can be kept: relative URLs are,
+// while absolute ones must use the http, https or mailto schemes.
+func isSafeURL(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// isTrackingPixel reports whether an image is too small to be seen, which is how
+// tracking pixels are embedded in posts.
+func isTrackingPixel(img *html.Node) bool {
+	for _, key := range []string{"width", "height"} {
+		value := strings.TrimSuffix(strings.TrimSpace(attribute(img, key)), "px")
+		if n, err := strconv.Atoi(value); err == nil && n <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// attribute returns the value of the attribute of node called key.
+func attribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// renderHTML serializes the given nodes back to HTML.
+func renderHTML(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		// rendering only fails when writing to the builder does, which never happens
+		html.Render(&b, node)
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+-- name: GetEnclosuresToDownload :many
+SELECT enclosures.id AS enclosure_id, enclosures.url AS enclosure_url, enclosures.media_type,
+    posts.id AS post_id, posts.title AS post_title, posts.published_at, feeds.name AS feed_name, feeds.url AS feed_url,
+    downloads.path AS download_path
+FROM enclosures
+INNER JOIN posts ON enclosures.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+LEFT JOIN downloads ON downloads.enclosure_id = enclosures.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.url, posts.published_at DESC, posts.id, enclosures.url;
+
+-- name: CreateDownload :exec
+INSERT INTO downloads (id, created_at, updated_at, enclosure_id, path, size, sha256)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (enclosure_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    path = EXCLUDED.path,
+    size = EXCLUDED.size,
+    sha256 = EXCLUDED.sha256;
+
+-- name: DeleteDownload :exec
+DELETE FROM downloads
+WHERE enclosure_id = $1;
+
+-- name: MoveDownloads :exec
+UPDATE downloads
+SET enclosure_id = target.id,
+    updated_at = @updated_at
+FROM enclosures AS source
+INNER JOIN posts AS source_post ON source.post_id = source_post.id
+INNER JOIN posts AS target_post ON target_post.guid = source_post.guid
+INNER JOIN enclosures AS target ON target.post_id = target_post.id AND target.url = source.url
+WHERE downloads.enclosure_id = source.id
+    AND source_post.feed_id = @from_feed_id
+    AND target_post.feed_id = @to_feed_id
+    AND NOT EXISTS (
+        SELECT 1
+        FROM downloads AS other
+        WHERE other.enclosure_id = target.id
+    );
+
+-- name: DeleteFeedDownloads :many
+WITH deleted AS (
+    DELETE FROM downloads
+    USING enclosures, posts
+    WHERE downloads.enclosure_id = enclosures.id
+        AND enclosures.post_id = posts.id
+        AND posts.feed_id = $1
+    RETURNING downloads.enclosure_id, downloads.path
+)
+SELECT DISTINCT deleted.path
+FROM deleted
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM downloads AS other
+    WHERE other.path = deleted.path
+        AND other.enclosure_id NOT IN (SELECT enclosure_id FROM deleted)
+);
@@ -0,0 +1,47 @@
+-- name: UpsertEnclosures :exec
+INSERT INTO enclosures (
+    id,
+    created_at,
+    updated_at,
+    post_id,
+    url,
+    media_type,
+    size,
+    duration,
+    episode
+)
+SELECT
+    enclosure.id,
+    @fetched_at::timestamp,
+    @fetched_at::timestamp,
+    enclosure.post_id,
+    enclosure.url,
+    enclosure.media_type,
+    NULLIF(enclosure.size, 0),
+    NULLIF(enclosure.duration, 0),
+    NULLIF(enclosure.episode, 0)
+FROM unnest(
+    @ids::uuid[],
+    @post_ids::uuid[],
+    @urls::text[],
+    @media_types::text[],
+    @sizes::bigint[],
+    @durations::integer[],
+    @episodes::integer[]
+) AS enclosure(id, post_id, url, media_type, size, duration, episode)
+ON CONFLICT (post_id, url) DO UPDATE
+SET media_type = EXCLUDED.media_type,
+    size = EXCLUDED.size,
+    duration = EXCLUDED.duration,
+    episode = EXCLUDED.episode,
+    updated_at = EXCLUDED.updated_at
+WHERE enclosures.media_type IS DISTINCT FROM EXCLUDED.media_type
+    OR enclosures.size IS DISTINCT FROM EXCLUDED.size
+    OR enclosures.duration IS DISTINCT FROM EXCLUDED.duration
+    OR enclosures.episode IS DISTINCT FROM EXCLUDED.episode;
+
+-- name: GetEnclosuresForPost :many
+SELECT *
+FROM enclosures
+WHERE post_id = $1
+ORDER BY url;
@@ -11,7 +11,10 @@ VALUES (
 RETURNING *;
 
 -- name: GetFeeds :many
-SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name,
+    feeds.last_fetched_at, feeds.last_succeeded_at, feeds.consecutive_failures, feeds.last_error,
+    feeds.title, feeds.description, feeds.site_url, feeds.language, feeds.image_url, feeds.generator,
+    feeds.disabled_at, feeds.disabled_reason
 FROM feeds
 INNER JOIN users ON feeds.user_id = users.id;
 
@@ -32,7 +35,8 @@ INNER JOIN users ON feed_record.user_id = users.id
 INNER JOIN feeds ON feed_record.feed_id = feeds.id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feeds.id AS feed_id, feeds.name AS feed_name, users.name AS user_name
+SELECT feeds.id AS feed_id, feeds.name AS feed_name, users.name AS user_name,
+    feeds.title, feeds.description, feeds.site_url, feeds.language, feeds.image_url, feeds.generator
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
@@ -42,19 +46,152 @@ WHERE feed_follows.user_id = $1;
 DELETE FROM feed_follows
 WHERE user_id = $1 AND feed_id = $2;
 
--- name: MarkFeedFetched :exec
+-- name: ClaimFeedsToFetch :many
 UPDATE feeds
 SET last_fetched_at = $1,
-    updated_at = $1
-WHERE id = $2;
+    updated_at = $1,
+    next_fetch_at = $3
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+        AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, url, etag, last_modified, consecutive_failures,
+    user_agent, request_headers, auth_username, auth_password_secret,
+    ttl_seconds, update_interval_seconds, skip_hours, skip_days;
+
+-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $1,
+    last_modified = $2
+WHERE id = $3;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_succeeded_at = $1,
+    next_fetch_at = $2
+WHERE id = $3;
+
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = $1,
+    next_fetch_at = $2
+WHERE id = $3;
 
--- name: GetNextFeedToFetch :one
-SELECT id, url
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $1,
+    description = $2,
+    site_url = $3,
+    language = $4,
+    image_url = $5,
+    generator = $6
+WHERE id = $7;
+
+-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET ttl_seconds = $1,
+    update_interval_seconds = $2,
+    skip_hours = $3,
+    skip_days = $4
+WHERE id = $5;
+
+-- name: GetFeedRequestSettings :one
+SELECT id, user_id, user_agent, request_headers, auth_username, auth_password_secret
 FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
+WHERE url = $1;
+
+-- name: UpdateFeedRequestSettings :exec
+UPDATE feeds
+SET user_agent = $1,
+    request_headers = $2,
+    auth_username = $3,
+    auth_password_secret = $4,
+    updated_at = $5
+WHERE id = $6;
 
--- name: CreatePost :exec
+-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = $1,
+    disabled_reason = $2,
+    updated_at = $1
+WHERE id = $3;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1,
+    updated_at = $2
+WHERE id = $3;
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = @to_feed_id
+WHERE feed_id = @from_feed_id
+    AND user_id NOT IN (
+        SELECT user_id
+        FROM feed_follows
+        WHERE feed_id = @to_feed_id
+    );
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = @to_feed_id
+WHERE feed_id = @from_feed_id
+    AND guid NOT IN (
+        SELECT guid
+        FROM posts
+        WHERE feed_id = @to_feed_id
+    );
+
+-- name: CopyFeedRequestSettings :exec
+UPDATE feeds
+SET user_agent = COALESCE(feeds.user_agent, source.user_agent),
+    request_headers = CASE
+        WHEN cardinality(feeds.request_headers) = 0 THEN source.request_headers
+        ELSE feeds.request_headers
+    END,
+    auth_username = CASE
+        WHEN feeds.auth_username IS NULL AND feeds.auth_password_secret IS NULL THEN source.auth_username
+        ELSE feeds.auth_username
+    END,
+    auth_password_secret = CASE
+        WHEN feeds.auth_username IS NULL AND feeds.auth_password_secret IS NULL THEN source.auth_password_secret
+        ELSE feeds.auth_password_secret
+    END,
+    updated_at = @updated_at
+FROM feeds AS source
+WHERE feeds.id = @to_feed_id
+    AND source.id = @from_feed_id;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: RekeyLegacyPosts :exec
+UPDATE posts
+SET guid = item.guid
+FROM unnest(
+    @urls::text[],
+    @guids::text[]
+) AS item(url, guid)
+WHERE posts.feed_id = @feed_id::uuid
+    AND posts.url = item.url
+    AND posts.guid = posts.url
+    AND item.guid <> item.url
+    AND NOT EXISTS (
+        SELECT 1
+        FROM posts AS other
+        WHERE other.feed_id = @feed_id::uuid AND other.guid = item.guid
+    );
+
+-- name: UpsertPosts :many
 INSERT INTO posts (
     id,
     created_at,
@@ -62,14 +199,81 @@ INSERT INTO posts (
     title,
     url,
     description,
+    description_text,
     published_at,
-    feed_id
+    feed_id,
+    guid,
+    author
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+SELECT
+    post.id,
+    @fetched_at::timestamp,
+    @fetched_at::timestamp,
+    post.title,
+    post.url,
+    post.description,
+    post.description_text,
+    post.published_at,
+    @feed_id::uuid,
+    post.guid,
+    post.author
+FROM unnest(
+    @ids::uuid[],
+    @titles::text[],
+    @urls::text[],
+    @descriptions::text[],
+    @description_texts::text[],
+    @published_ats::timestamp[],
+    @guids::text[],
+    @authors::text[]
+) AS post(id, title, url, description, description_text, published_at, guid, author)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    description_text = EXCLUDED.description_text,
+    author = EXCLUDED.author,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.title <> EXCLUDED.title
+    OR posts.url <> EXCLUDED.url
+    OR posts.description <> EXCLUDED.description
+    OR posts.description_text <> EXCLUDED.description_text
+    OR posts.author <> EXCLUDED.author
+RETURNING id, guid, (xmax = 0) AS inserted;
+
+-- name: GetPostIDsByGUID :many
+SELECT id, guid
+FROM posts
+WHERE feed_id = @feed_id AND guid = ANY(@guids::text[]);
 
 -- name: GetPostsForUser :many
-SELECT title, published_at, url, description
+SELECT id, title, published_at, url, description, description_text, feed_id, post_index
+FROM (
+    SELECT posts.id, posts.title, posts.published_at, posts.url, posts.description, posts.description_text,
+        posts.feed_id,
+        row_number() OVER (ORDER BY posts.published_at DESC, posts.id) AS post_index,
+        row_number() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC, posts.id) AS feed_rank
+    FROM posts
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    WHERE feed_follows.user_id = @user_id
+) AS ranked
+WHERE feed_rank <= @posts_per_feed::bigint
+ORDER BY post_index;
+
+-- name: GetPostForUser :one
+SELECT posts.id, posts.title, posts.url, posts.published_at, posts.author, posts.description,
+    feeds.name AS feed_name
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1 AND posts.id = $2;
+
+-- name: GetNthLatestPostForUser :one
+SELECT posts.id, posts.title, posts.url, posts.published_at, posts.author, posts.description,
+    feeds.name AS feed_name
 FROM posts
-WHERE feed_id = $1
-ORDER BY published_at DESC
-LIMIT $2;
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC, posts.id
+LIMIT 1 OFFSET $2;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN guid TEXT;
+
+UPDATE posts
+SET guid = url;
+
+ALTER TABLE posts
+ALTER COLUMN guid SET NOT NULL,
+ADD CONSTRAINT feed_guid UNIQUE (feed_id, guid),
+DROP CONSTRAINT posts_url_key;
+
+-- +goose Down
+ALTER TABLE posts
+DROP CONSTRAINT feed_guid,
+DROP COLUMN guid,
+ADD CONSTRAINT posts_url_key UNIQUE (url);
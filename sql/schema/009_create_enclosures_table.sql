@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE enclosures (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  media_type TEXT NOT NULL,
+  size BIGINT,
+  duration INTEGER,
+  episode INTEGER,
+  CONSTRAINT post_enclosure_url UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE enclosures;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE downloads (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  enclosure_id UUID NOT NULL UNIQUE REFERENCES enclosures(id) ON DELETE CASCADE,
+  path TEXT NOT NULL,
+  size BIGINT NOT NULL,
+  sha256 TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE downloads;
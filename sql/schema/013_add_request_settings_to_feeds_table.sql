@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN user_agent TEXT,
+ADD COLUMN request_headers TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN auth_username TEXT,
+ADD COLUMN auth_password_secret TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN user_agent,
+DROP COLUMN request_headers,
+DROP COLUMN auth_username,
+DROP COLUMN auth_password_secret;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN ttl_seconds INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN update_interval_seconds INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN skip_hours INTEGER[] NOT NULL DEFAULT '{}',
+ADD COLUMN skip_days INTEGER[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN ttl_seconds,
+DROP COLUMN update_interval_seconds,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days;